@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -29,52 +30,57 @@ func restExample() {
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
 	markets := make([]string, 0, 5)
 	markets = append(markets, "BTC-AUD")
 
-	o, err := c.Order.ListOrders("BTC-AUD", "close", 0, 0, 10)
+	o, _, err := c.Order.ListOrders(ctx, "BTC-AUD", "all", 0, 0, 10)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 	log.Printf("%+v \n\n\n", o)
 
-	cpo, err := c.Order.CancelOpenOrdersByPairs(markets)
+	cpo, err := c.Order.CancelOpenOrdersByPairs(ctx, markets)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", cpo)
 
-	cao, err := c.Order.CancelAllOpenOrders()
+	cao, err := c.Order.CancelAllOpenOrders(ctx)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", cao)
 
-	no, err := c.Order.PlaceNewOrder("BTC-AUD", 0.01, 1, "Limit", "Bid", 0.0, 0.0, "", false, "", "")
+	order := btcmarkets.NewLimitOrder("BTC-AUD", btcmarkets.SideBid, btcmarkets.MustParseDecimal("0.01"), btcmarkets.NewDecimalFromInt(1)).
+		WithPostOnly()
+	no, err := c.Order.PlaceNewOrder(ctx, order)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", no)
 
-	da, err := c.FundManagement.GetDepositeAddress("ltc", 78234876, 0, 10)
+	da, err := c.FundManagement.GetDepositeAddress(ctx, "ltc", 78234876, 0, 10)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", da)
 
-	wf, err := c.FundManagement.GetWithdrawalFees()
+	wf, err := c.FundManagement.GetWithdrawalFees(ctx)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", wf)
 
-	la, err := c.FundManagement.ListAssets()
+	la, err := c.FundManagement.ListAssets(ctx)
 	if err != nil {
 		log.Println(err.Error())
 	}
 	log.Printf("%+v \n\n\n", la)
 
-	tf, err := c.Account.GetTradingFees()
+	tf, err := c.Account.GetTradingFees(ctx)
 	if err != nil {
 		log.Println(err.Error())
 	}
@@ -51,7 +51,7 @@ func websocketExample() {
 	}
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
-	ch, err := c.WebSocket.Subscribe(ctx, subm)
+	s, err := c.WebSocket.SubscribeEvents(ctx, subm)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -61,8 +61,25 @@ func websocketExample() {
 		ctxCancel()
 	}()
 
-	for i := 0; i < 50; i++ {
-		log.Print(string(<-ch))
+	go func() {
+		for st := range s.Status() {
+			log.Printf("connection %s %v", st.State, st.Err)
+		}
+	}()
+
+	for e := range s.Events() {
+		switch e := e.(type) {
+		case *btcmarkets.BTCMWSTickEvent:
+			log.Printf("tick %s bid %s ask %s", e.MarketID, e.BestBid, e.BestAsk)
+		case *btcmarkets.BTCMWSTradeEvent:
+			log.Printf("trade %s %s %s @ %s", e.MarketID, e.Side, e.Volume, e.Price)
+		case *btcmarkets.BTCMWSOrderChangeEvent:
+			for _, f := range e.Trades {
+				log.Printf("order %s filled %s @ %s fee %s (%s)", e.OrderID, f.Volume, f.Price, f.Fee, f.LiquidityType)
+			}
+		default:
+			log.Printf("%+v", e)
+		}
 	}
 	log.Print("Done...")
 }
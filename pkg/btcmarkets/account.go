@@ -1,6 +1,7 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
@@ -12,23 +13,23 @@ import (
 
 // TradingFeeData stores trading fee data
 type TradingFeeData struct {
-	MakerFeeRate float64 `json:"makerFeeRate,string"`
-	TakerFeeRate float64 `json:"takerFeeRate,string"`
+	MakerFeeRate Decimal `json:"makerFeeRate"`
+	TakerFeeRate Decimal `json:"takerFeeRate"`
 	MarketID     string  `json:"marketId"`
 }
 
 // TradingFeeResponse stores trading fee data
 type TradingFeeResponse struct {
-	MonthlyVolume float64          `json:"volume30Day,string"`
+	MonthlyVolume Decimal          `json:"volume30Day"`
 	FeeByMarkets  []TradingFeeData `json:"FeeByMarkets"`
 }
 
 // AccountBalance struct to represent Account balance JSON response
 type AccountBalance struct {
-	AssetName string `json:"assetName"`
-	Available string `json:"available"`
-	Balance   string `json:"balance"`
-	Locked    string `json:"locked"`
+	AssetName string  `json:"assetName"`
+	Available Decimal `json:"available"`
+	Balance   Decimal `json:"balance"`
+	Locked    Decimal `json:"locked"`
 }
 
 // TransactionData stores data from past transactions
@@ -37,8 +38,8 @@ type TransactionData struct {
 	CreationTime time.Time `json:"creationTime"`
 	Description  string    `json:"description"`
 	AssetName    string    `json:"assetName"`
-	Amount       float64   `json:"amount,string"`
-	Balance      float64   `json:"balance,string"`
+	Amount       Decimal   `json:"amount"`
+	Balance      Decimal   `json:"balance"`
 	FeeType      string    `json:"type"`
 	RecordType   string    `json:"recordType"`
 	ReferrenceID string    `json:"referrenceId"`
@@ -50,10 +51,10 @@ type AccountServiceOp struct {
 }
 
 // GetTradingFees Returns 30 day trading fee volume plus trading fee per market covering marker and taker.
-func (a *AccountServiceOp) GetTradingFees() (TradingFeeResponse, error) {
+func (a *AccountServiceOp) GetTradingFees(ctx context.Context) (TradingFeeResponse, error) {
 	var tf TradingFeeResponse
 
-	req, err := a.client.NewRequest(http.MethodGet, btcMarketsTradingFees, nil)
+	req, err := a.client.NewRequest(ctx, http.MethodGet, btcMarketsTradingFees, nil)
 	if err != nil {
 		return tf, err
 	}
@@ -73,10 +74,10 @@ func (a *AccountServiceOp) GetTradingFees() (TradingFeeResponse, error) {
 // available, and locked amount for each asset due to open orders or
 // pending withdrawals. This formula represents the relationship
 // between those three elements: balance = available + locked
-func (a *AccountServiceOp) GetBalances() ([]AccountBalance, error) {
+func (a *AccountServiceOp) GetBalances(ctx context.Context) ([]AccountBalance, error) {
 	var gb []AccountBalance
 
-	req, err := a.client.NewRequest(http.MethodGet, btcMarketsAccountBalance, nil)
+	req, err := a.client.NewRequest(ctx, http.MethodGet, btcMarketsAccountBalance, nil)
 	if err != nil {
 		return gb, err
 	}
@@ -90,11 +91,13 @@ func (a *AccountServiceOp) GetBalances() ([]AccountBalance, error) {
 }
 
 // ListTransactions Returns detail ledger recoerds for underlying wallets. This API supports pagination.
-func (a *AccountServiceOp) ListTransactions(assetName string, before, after int64, limit int32) ([]TransactionData, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the adjacent pages; see also
+// TransactionIterator.
+func (a *AccountServiceOp) ListTransactions(ctx context.Context, assetName string, before, after int64, limit int32) ([]TransactionData, Cursor, error) {
 	var lt []TransactionData
 
 	if (before > 0) && (after > 0) {
-		return lt, errors.New("BTCMarkets only supports either before or after, not both")
+		return lt, Cursor{}, errors.New("BTCMarkets only supports either before or after, not both")
 	}
 
 	params := url.Values{}
@@ -112,15 +115,61 @@ func (a *AccountServiceOp) ListTransactions(assetName string, before, after int6
 		params.Add("limit", strconv.Itoa(int(limit)))
 	}
 
-	req, err := a.client.NewRequest(http.MethodGet, path.Join(btcMarketsTransactions, "?"+params.Encode()), nil)
+	req, err := a.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsTransactions, "?"+params.Encode()), nil)
 	if err != nil {
-		return lt, err
+		return lt, Cursor{}, err
 	}
 
-	_, err = a.client.DoAuthenticated(req, nil, &lt)
+	resp, err := a.client.DoAuthenticated(req, nil, &lt)
 	if err != nil {
-		return lt, err
+		return lt, Cursor{}, err
 	}
 
-	return lt, nil
+	return lt, cursorFromResponse(resp), nil
+}
+
+// TransactionIterator walks the ledger records of the account, newest
+// first, fetching one page at a time.
+type TransactionIterator struct {
+	pager
+	svc       *AccountServiceOp
+	assetName string
+	buf       []TransactionData
+	cur       TransactionData
+
+	// Stop, if set, ends the walk at the first transaction it returns true for.
+	Stop func(TransactionData) bool
+}
+
+// IterateTransactions returns an iterator over the ledger records of
+// assetName, or of every asset when assetName is empty.
+func (a *AccountServiceOp) IterateTransactions(ctx context.Context, assetName string, opts IteratorOptions) *TransactionIterator {
+	return &TransactionIterator{pager: newPager(ctx, opts), svc: a, assetName: assetName}
+}
+
+// Next advances to the next transaction and reports whether there is one.
+func (it *TransactionIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for len(it.buf) == 0 {
+		if !it.fetchPage(it.fetch) {
+			return false
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return !it.stopAt(it.cur.CreationTime, it.Stop != nil && it.Stop(it.cur))
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIterator) Transaction() TransactionData {
+	return it.cur
+}
+
+func (it *TransactionIterator) fetch(after int64, limit int) (int, Cursor, error) {
+	var c Cursor
+	var err error
+	it.buf, c, err = it.svc.ListTransactions(it.ctx, it.assetName, 0, after, int32(limit))
+	return len(it.buf), c, err
 }
@@ -1,6 +1,7 @@
 package btcmarkets
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -41,7 +42,7 @@ func TestAccountGetTradingFees(t *testing.T) {
 	}
 	mux.HandleFunc("/v3/accounts/me/trading-fees", mockserver)
 
-	_, err = client.Account.GetTradingFees()
+	_, err = client.Account.GetTradingFees(context.Background())
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -74,7 +75,7 @@ func TestGetBalance(t *testing.T) {
 	}
 	mux.HandleFunc("/v3/accounts/me/balances", mockServer)
 
-	ab, err := client.Account.GetBalances()
+	ab, err := client.Account.GetBalances(context.Background())
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -120,7 +121,7 @@ func TestGetBalanceWithRateLimit(t *testing.T) {
 
 	before := time.Now()
 	for i := 0; i <= 1; i++ {
-		ab, err := client.Account.GetBalances()
+		ab, err := client.Account.GetBalances(context.Background())
 		if err != nil {
 			t.Errorf(err.Error())
 		}
@@ -1,7 +1,9 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"path"
 	"strings"
@@ -10,16 +12,16 @@ import (
 
 // BatchPlaceData stores data for placed batch orders
 type BatchPlaceData struct {
-	OrderID       string    `json:"orderId"`
-	MarketID      string    `json:"marketId"`
-	Side          string    `json:"side"`
-	Type          string    `json:"type"`
-	CreationTime  time.Time `json:"creationTime"`
-	Price         float64   `json:"price,string"`
-	Amount        float64   `json:"amount,string"`
-	OpenAmount    float64   `json:"openAmount,string"`
-	Status        string    `json:"status"`
-	ClientOrderID string    `json:"clientOrderId"`
+	OrderID       string      `json:"orderId"`
+	MarketID      string      `json:"marketId"`
+	Side          Side        `json:"side"`
+	Type          OrderType   `json:"type"`
+	CreationTime  time.Time   `json:"creationTime"`
+	Price         Decimal     `json:"price"`
+	Amount        Decimal     `json:"amount"`
+	OpenAmount    Decimal     `json:"openAmount"`
+	Status        OrderStatus `json:"status"`
+	ClientOrderID string      `json:"clientOrderId"`
 }
 
 // CancelBatch stores data for batch cancel request
@@ -29,18 +31,59 @@ type CancelBatch struct {
 }
 
 // PlaceBatch stores data for place batch request
+// Optional prices and amounts are pointers so that they are left out of the
+// request when unset.
 type PlaceBatch struct {
-	MarketID      string  `json:"marketId"`
-	Price         float64 `json:"price"`
-	Amount        float64 `json:"amount"`
-	OrderType     string  `json:"type"`
-	Side          string  `json:"side"`
-	TriggerPrice  float64 `json:"triggerPrice,omitempty"`
-	TriggerAmount float64 `json:"triggerAmount,omitempty"`
-	TimeInForce   string  `json:"timeInForce,omitempty"`
-	PostOnly      bool    `json:"postOnly,omitempty"`
-	SelfTrade     string  `json:"selfTrade,omitempty"`
-	ClientOrderID string  `json:"clientOrderId,omitempty"`
+	MarketID      string      `json:"marketId"`
+	Price         *Decimal    `json:"price,omitempty"`
+	Amount        Decimal     `json:"amount"`
+	OrderType     OrderType   `json:"type"`
+	Side          Side        `json:"side"`
+	TriggerPrice  *Decimal    `json:"triggerPrice,omitempty"`
+	TriggerAmount *Decimal    `json:"triggerAmount,omitempty"`
+	TimeInForce   TimeInForce `json:"timeInForce,omitempty"`
+	PostOnly      bool        `json:"postOnly,omitempty"`
+	SelfTrade     SelfTrade   `json:"selfTrade,omitempty"`
+	ClientOrderID string      `json:"clientOrderId,omitempty"`
+}
+
+// validate checks the enumerated fields of the order, and that it holds the
+// prices its order type requires and none it does not accept, like
+// NewOrderRequest.Validate. Errors match ErrValidation.
+func (p PlaceBatch) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("btcmarkets: "+format+": %w", append(args, ErrValidation)...)
+	}
+
+	if err := p.OrderType.Validate(); err != nil {
+		return err
+	}
+	if err := p.Side.Validate(); err != nil {
+		return err
+	}
+	if p.OrderType.RequiresPrice() {
+		if p.Price == nil || p.Price.Sign() <= 0 {
+			return invalid("%s orders require a positive price", p.OrderType)
+		}
+	} else if p.Price != nil {
+		return invalid("%s orders do not accept a price", p.OrderType)
+	}
+	if p.OrderType.RequiresTriggerPrice() {
+		if p.TriggerPrice == nil || p.TriggerPrice.Sign() <= 0 {
+			return invalid("%s orders require a positive trigger price", p.OrderType)
+		}
+	} else if p.TriggerPrice != nil {
+		return invalid("%s orders do not accept a trigger price", p.OrderType)
+	}
+	if p.TimeInForce != "" {
+		if err := p.TimeInForce.Validate(); err != nil {
+			return err
+		}
+	}
+	if p.SelfTrade != "" {
+		return p.SelfTrade.Validate()
+	}
+	return nil
 }
 
 // BatchPlaceCancelResponse stores place and cancel batch data
@@ -68,11 +111,11 @@ type BatchOrderServiceOp struct {
 // and a cancellation. There are restrictions on the number
 // of items in a batch (currently set to 10) so a batch can c
 // ontain up to 4 items in any form that is needed
-func (b *BatchOrderServiceOp) BatchPlaceCancelOrders(cancelOrders []CancelBatch, placeOrders []PlaceBatch) (BatchPlaceCancelResponse, error) {
+func (b *BatchOrderServiceOp) BatchPlaceCancelOrders(ctx context.Context, cancelOrders []CancelBatch, placeOrders []PlaceBatch) (BatchPlaceCancelResponse, error) {
 	var resp BatchPlaceCancelResponse
 	var orderRequests []interface{}
 
-	if len(cancelOrders)+len(placeOrders) > 4 {
+	if len(cancelOrders)+len(placeOrders) > batchPlaceCancelMax {
 		return resp, errors.New("Cannot supply more than 4 orders at a time")
 	}
 
@@ -83,10 +126,19 @@ func (b *BatchOrderServiceOp) BatchPlaceCancelOrders(cancelOrders []CancelBatch,
 		if placeOrders[y].ClientOrderID == "" {
 			return resp, errors.New("placeorders must have clientorderids filled")
 		}
+		if err := placeOrders[y].validate(); err != nil {
+			return resp, err
+		}
+		if b.client.enforceMarketRules {
+			p := placeOrders[y]
+			if err := b.client.MarketRules.validate(ctx, p.MarketID, &p.Amount, p.Price, p.TriggerPrice); err != nil {
+				return resp, err
+			}
+		}
 		orderRequests = append(orderRequests, PlaceOrderMethod{PlaceOrder: placeOrders[y]})
 	}
 
-	req, err := b.client.NewRequest(http.MethodPost, btcMarketsBatchOrders, orderRequests)
+	req, err := b.client.NewRequest(ctx, http.MethodPost, btcMarketsBatchOrders, orderRequests)
 	if err != nil {
 		return resp, err
 	}
@@ -99,14 +151,14 @@ func (b *BatchOrderServiceOp) BatchPlaceCancelOrders(cancelOrders []CancelBatch,
 }
 
 // GetBatchOrders gets batch trades
-func (b *BatchOrderServiceOp) GetBatchOrders(ids []string) (BatchTradeResponse, error) {
+func (b *BatchOrderServiceOp) GetBatchOrders(ctx context.Context, ids []string) (BatchTradeResponse, error) {
 	var resp BatchTradeResponse
-	if len(ids) > 50 {
+	if len(ids) > batchGetMax {
 		return resp, errors.New("batchtrades can only handle 50 ids at a time")
 	}
 	marketIDs := strings.Join(ids, ",")
 
-	req, err := b.client.NewRequest(http.MethodGet, path.Join(btcMarketsBatchOrders, marketIDs), nil)
+	req, err := b.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsBatchOrders, marketIDs), nil)
 	if err != nil {
 		return resp, err
 	}
@@ -120,11 +172,14 @@ func (b *BatchOrderServiceOp) GetBatchOrders(ids []string) (BatchTradeResponse,
 }
 
 // CancelBatchOrders cancels given ids
-func (b *BatchOrderServiceOp) CancelBatchOrders(ids []string) (BatchCancelResponse, error) {
+func (b *BatchOrderServiceOp) CancelBatchOrders(ctx context.Context, ids []string) (BatchCancelResponse, error) {
 	var resp BatchCancelResponse
+	if len(ids) > batchCancelMax {
+		return resp, errors.New("batch cancel can only handle 10 ids at a time")
+	}
 	marketIDs := strings.Join(ids, ",")
 
-	req, err := b.client.NewRequest(http.MethodDelete, path.Join(btcMarketsBatchOrders, marketIDs), nil)
+	req, err := b.client.NewRequest(ctx, http.MethodDelete, path.Join(btcMarketsBatchOrders, marketIDs), nil)
 	if err != nil {
 		return resp, err
 	}
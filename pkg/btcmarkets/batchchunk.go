@@ -0,0 +1,273 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Maximum number of items accepted by the batch order endpoints in a single
+// request.
+const (
+	batchPlaceCancelMax = 4
+	batchGetMax         = 50
+	batchCancelMax      = 10
+)
+
+// BatchItemStatus is the outcome of one item of a chunked batch operation.
+type BatchItemStatus int
+
+// Batch item outcomes
+const (
+	// BatchItemUnprocessed means the exchange did not process the item;
+	// BatchItem.Code and BatchItem.Message tell why.
+	BatchItemUnprocessed BatchItemStatus = iota
+	// BatchItemPlaced means the order was placed.
+	BatchItemPlaced
+	// BatchItemCancelled means the order was cancelled.
+	BatchItemCancelled
+	// BatchItemFound means the order was returned by a lookup.
+	BatchItemFound
+	// BatchItemFailed means the item was invalid or the request of its
+	// chunk failed; BatchItem.Err tells why. When the request failed
+	// without an answer, e.g. with a timeout or a 5xx error, the item may
+	// still have been processed by the exchange.
+	BatchItemFailed
+	// BatchItemNotSent means the item was not sent because an earlier
+	// item or chunk failed.
+	BatchItemNotSent
+)
+
+func (s BatchItemStatus) String() string {
+	switch s {
+	case BatchItemUnprocessed:
+		return "Unprocessed"
+	case BatchItemPlaced:
+		return "Placed"
+	case BatchItemCancelled:
+		return "Cancelled"
+	case BatchItemFound:
+		return "Found"
+	case BatchItemFailed:
+		return "Failed"
+	case BatchItemNotSent:
+		return "NotSent"
+	}
+	return fmt.Sprintf("BatchItemStatus(%d)", int(s))
+}
+
+// BatchItem is the outcome of one order of a chunked batch operation.
+type BatchItem struct {
+	Status BatchItemStatus
+
+	// Order is set when the order was placed or found.
+	Order *BatchPlaceData
+
+	// Cancel is set when the order was cancelled.
+	Cancel *CancelOrderResp
+
+	// Code and Message are set when the item was unprocessed.
+	Code    string
+	Message string
+
+	// Err is set when the item failed.
+	Err error
+}
+
+// BatchResult merges the responses of every chunk of a batch operation.
+type BatchResult struct {
+	PlacedOrders      []BatchPlaceData
+	CancelledOrders   []CancelOrderResp
+	Orders            []BatchPlaceData
+	UnprocessedOrders []UnprocessedBatchResp
+
+	// Items maps the ids of every order of the operation, i.e. its
+	// clientOrderId and its orderId when known, to its outcome, including
+	// the orders that failed or were not sent.
+	Items map[string]BatchItem
+}
+
+func newBatchResult() BatchResult {
+	return BatchResult{Items: make(map[string]BatchItem)}
+}
+
+func (r *BatchResult) set(item BatchItem, ids ...string) {
+	for _, id := range ids {
+		if id != "" {
+			r.Items[id] = item
+		}
+	}
+}
+
+// fail records the orders identified by ids as failed with err.
+func (r *BatchResult) fail(err error, ids ...string) {
+	r.set(BatchItem{Status: BatchItemFailed, Err: err}, ids...)
+}
+
+// notSent records the orders identified by ids as not sent.
+func (r *BatchResult) notSent(ids ...string) {
+	r.set(BatchItem{Status: BatchItemNotSent}, ids...)
+}
+
+func (r *BatchResult) addPlaced(orders []BatchPlaceData, status BatchItemStatus) {
+	for i := range orders {
+		o := orders[i]
+		r.set(BatchItem{Status: status, Order: &o}, o.ClientOrderID, o.OrderID)
+	}
+}
+
+func (r *BatchResult) addCancelled(orders []CancelOrderResp) {
+	r.CancelledOrders = append(r.CancelledOrders, orders...)
+	for i := range orders {
+		c := orders[i]
+		r.set(BatchItem{Status: BatchItemCancelled, Cancel: &c}, c.ClientOrderID, c.OrderID)
+	}
+}
+
+func (r *BatchResult) addUnprocessed(unprocessed []UnprocessedBatchResp) {
+	r.UnprocessedOrders = append(r.UnprocessedOrders, unprocessed...)
+	for _, u := range unprocessed {
+		r.set(BatchItem{Status: BatchItemUnprocessed, Code: u.Code, Message: u.Message}, u.RequestID)
+	}
+}
+
+// chunkError reports the failure of one chunk of a batch operation.
+func chunkError(n, total int, err error) error {
+	return fmt.Errorf("btcmarkets: batch chunk %d of %d failed: %w", n, total, err)
+}
+
+// PlaceCancelChunked places and cancels any number of orders, splitting them
+// into chunks of at most 4 items sent one after the other through
+// BatchPlaceCancelOrders, each waiting on the batch rate limiter. Orders to
+// place without a clientOrderId are given a generated one so their outcome
+// can be found in BatchResult.Items. Every order is validated before the
+// first chunk is sent; when one is invalid, nothing is sent and the error
+// names its index in placeOrders.
+//
+// When a chunk fails, the result of the previous chunks is returned with
+// the error and the following chunks are not sent. The orders of the failed
+// chunk are recorded in BatchResult.Items as BatchItemFailed, and those of
+// the following chunks as BatchItemNotSent.
+func (b *BatchOrderServiceOp) PlaceCancelChunked(ctx context.Context, cancelOrders []CancelBatch, placeOrders []PlaceBatch) (BatchResult, error) {
+	res := newBatchResult()
+
+	places := make([]PlaceBatch, len(placeOrders))
+	copy(places, placeOrders)
+	for i := range places {
+		if places[i].ClientOrderID == "" {
+			places[i].ClientOrderID = b.client.newClientOrderID()
+		}
+	}
+	for i, p := range places {
+		err := p.validate()
+		if err == nil && b.client.enforceMarketRules {
+			err = b.client.MarketRules.validate(ctx, p.MarketID, &p.Amount, p.Price, p.TriggerPrice)
+		}
+		if err != nil {
+			err = fmt.Errorf("btcmarkets: batch order %d: %w", i, err)
+			res.notSent(batchIDs(cancelOrders, places)...)
+			res.fail(err, p.ClientOrderID)
+			return res, err
+		}
+	}
+
+	total := (len(cancelOrders) + len(places) + batchPlaceCancelMax - 1) / batchPlaceCancelMax
+	for n := 1; len(cancelOrders)+len(places) > 0; n++ {
+		size := batchPlaceCancelMax
+		nc := min(size, len(cancelOrders))
+		np := min(size-nc, len(places))
+
+		resp, err := b.BatchPlaceCancelOrders(ctx, cancelOrders[:nc], places[:np])
+		if err != nil {
+			err = chunkError(n, total, err)
+			res.fail(err, batchIDs(cancelOrders[:nc], places[:np])...)
+			res.notSent(batchIDs(cancelOrders[nc:], places[np:])...)
+			return res, err
+		}
+		cancelOrders, places = cancelOrders[nc:], places[np:]
+
+		res.PlacedOrders = append(res.PlacedOrders, resp.PlacedOrders...)
+		res.addPlaced(resp.PlacedOrders, BatchItemPlaced)
+		res.addCancelled(resp.CancelledOrders)
+		res.addUnprocessed(resp.UnprocessedOrders)
+	}
+	return res, nil
+}
+
+// batchIDs returns the ids of the given batch items.
+func batchIDs(cancels []CancelBatch, places []PlaceBatch) []string {
+	var ids []string
+	for _, c := range cancels {
+		ids = append(ids, c.OrderID, c.ClientOrderID)
+	}
+	for _, p := range places {
+		ids = append(ids, p.ClientOrderID)
+	}
+	return ids
+}
+
+// GetOrdersChunked returns any number of orders by id, fetching them in
+// chunks of at most 50 ids through GetBatchOrders. Failures are handled
+// like in PlaceCancelChunked.
+func (b *BatchOrderServiceOp) GetOrdersChunked(ctx context.Context, ids []string) (BatchResult, error) {
+	res := newBatchResult()
+
+	err := res.chunkIDs(ids, batchGetMax, func(chunk []string) error {
+		resp, err := b.GetBatchOrders(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		res.Orders = append(res.Orders, resp.Orders...)
+		res.addPlaced(resp.Orders, BatchItemFound)
+		res.addUnprocessed(resp.UnprocessedRequests)
+		return nil
+	})
+	return res, err
+}
+
+// CancelOrdersChunked cancels any number of orders by id, in chunks of at
+// most 10 ids sent through CancelBatchOrders. Failures are handled like in
+// PlaceCancelChunked.
+func (b *BatchOrderServiceOp) CancelOrdersChunked(ctx context.Context, ids []string) (BatchResult, error) {
+	res := newBatchResult()
+
+	err := res.chunkIDs(ids, batchCancelMax, func(chunk []string) error {
+		resp, err := b.CancelBatchOrders(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		res.addCancelled(resp.CancelOrders)
+		res.addUnprocessed(resp.UnprocessedRequests)
+		return nil
+	})
+	return res, err
+}
+
+// chunkIDs calls fn with consecutive chunks of at most size ids, stopping
+// at the first error. The ids of the failed chunk and of the following ones
+// are then recorded as failed and not sent.
+func (r *BatchResult) chunkIDs(ids []string, size int, fn func([]string) error) error {
+	if len(ids) == 0 {
+		return errors.New("at least 1 id is required")
+	}
+
+	total := (len(ids) + size - 1) / size
+	for n := 1; len(ids) > 0; n++ {
+		chunk := ids[:min(size, len(ids))]
+		if err := fn(chunk); err != nil {
+			err = chunkError(n, total, err)
+			r.fail(err, chunk...)
+			r.notSent(ids[len(chunk):]...)
+			return err
+		}
+		ids = ids[len(chunk):]
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
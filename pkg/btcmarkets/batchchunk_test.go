@@ -0,0 +1,240 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestPlaceBatchValidate(t *testing.T) {
+	one := MustParseDecimal("1")
+
+	tests := []struct {
+		name  string
+		order PlaceBatch
+		valid bool
+	}{
+		{"Limit", PlaceBatch{OrderType: OrderTypeLimit, Side: SideBid, Price: &one, Amount: one}, true},
+		{"Market", PlaceBatch{OrderType: OrderTypeMarket, Side: SideBid, Amount: one}, true},
+		{"Stop", PlaceBatch{OrderType: OrderTypeStop, Side: SideAsk, TriggerPrice: &one, Amount: one}, true},
+		{"Limit without price", PlaceBatch{OrderType: OrderTypeLimit, Side: SideBid, Amount: one}, false},
+		{"Market with price", PlaceBatch{OrderType: OrderTypeMarket, Side: SideBid, Price: &one, Amount: one}, false},
+		{"Stop without trigger", PlaceBatch{OrderType: OrderTypeStop, Side: SideAsk, Amount: one}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.order.validate()
+			if tt.valid && err != nil {
+				t.Errorf("validate() error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrValidation) {
+				t.Errorf("validate() error = %v; want ErrValidation", err)
+			}
+		})
+	}
+}
+
+func TestPlaceCancelChunked(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.limiters[EndpointBatch] = rate.NewLimiter(rate.Inf, 1)
+
+	var chunks []int
+	mux.HandleFunc("/v3/batchorders", func(w http.ResponseWriter, r *http.Request) {
+		var items []map[string]map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			t.Error(err)
+			return
+		}
+		chunks = append(chunks, len(items))
+
+		var resp BatchPlaceCancelResponse
+		for _, it := range items {
+			if p, ok := it["placeOrder"]; ok {
+				id := p["clientOrderId"].(string)
+				if id == "reject" {
+					resp.UnprocessedOrders = append(resp.UnprocessedOrders, UnprocessedBatchResp{Code: "InsufficientFund", RequestID: id})
+					continue
+				}
+				resp.PlacedOrders = append(resp.PlacedOrders, BatchPlaceData{OrderID: "o-" + id, ClientOrderID: id, Status: OrderStatusAccepted})
+			}
+			if c, ok := it["cancelOrder"]; ok {
+				resp.CancelledOrders = append(resp.CancelledOrders, CancelOrderResp{OrderID: c["orderId"].(string)})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	cancels := []CancelBatch{{OrderID: "c1"}, {OrderID: "c2"}}
+	places := []PlaceBatch{{ClientOrderID: "reject"}}
+	for i := 0; i < 8; i++ {
+		places = append(places, PlaceBatch{ClientOrderID: fmt.Sprint("p", i)})
+	}
+	places = append(places, PlaceBatch{})
+	for i := range places {
+		places[i].MarketID, places[i].OrderType, places[i].Side = "BTC-AUD", OrderTypeMarket, SideBid
+		places[i].Amount = MustParseDecimal("1")
+	}
+
+	res, err := client.Batch.PlaceCancelChunked(context.Background(), cancels, places)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(chunks) != "[4 4 4]" {
+		t.Errorf("chunks = %v; want [4 4 4]", chunks)
+	}
+	if len(res.PlacedOrders) != 9 || len(res.CancelledOrders) != 2 || len(res.UnprocessedOrders) != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if it := res.Items["p7"]; it.Status != BatchItemPlaced || it.Order.OrderID != "o-p7" {
+		t.Errorf("Items[p7] = %+v", it)
+	}
+	if it := res.Items["c2"]; it.Status != BatchItemCancelled {
+		t.Errorf("Items[c2] = %+v", it)
+	}
+	if it := res.Items["reject"]; it.Status != BatchItemUnprocessed || it.Code != "InsufficientFund" {
+		t.Errorf("Items[reject] = %+v", it)
+	}
+	if places[9].ClientOrderID != "" || len(res.Items) != 9*2+2+1 {
+		t.Errorf("generated id should not be set on the caller's order; %d items", len(res.Items))
+	}
+}
+
+func TestPlaceCancelChunkedFailure(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.limiters[EndpointBatch] = rate.NewLimiter(rate.Inf, 1)
+	client.retryPolicy = nil
+
+	requests := 0
+	mux.HandleFunc("/v3/batchorders", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var items []map[string]map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			t.Error(err)
+			return
+		}
+		if requests == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var resp BatchPlaceCancelResponse
+		for _, it := range items {
+			if p, ok := it["placeOrder"]; ok {
+				id := p["clientOrderId"].(string)
+				resp.PlacedOrders = append(resp.PlacedOrders, BatchPlaceData{OrderID: "o-" + id, ClientOrderID: id})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	var places []PlaceBatch
+	for i := 0; i < 10; i++ {
+		places = append(places, PlaceBatch{ClientOrderID: fmt.Sprint("p", i), MarketID: "BTC-AUD",
+			OrderType: OrderTypeMarket, Side: SideBid, Amount: MustParseDecimal("1")})
+	}
+
+	ctx := context.Background()
+	res, err := client.Batch.PlaceCancelChunked(ctx, nil, places)
+	if err == nil || requests != 2 {
+		t.Fatalf("error = %v after %d requests; want the second chunk to fail", err, requests)
+	}
+	for i, want := range []BatchItemStatus{BatchItemPlaced, BatchItemFailed, BatchItemNotSent} {
+		for _, id := range []string{fmt.Sprint("p", 4*i), fmt.Sprint("p", 4*i+1)} {
+			if it := res.Items[id]; it.Status != want || (want == BatchItemFailed) != (it.Err != nil) {
+				t.Errorf("Items[%s] = %+v; want %v", id, it, want)
+			}
+		}
+	}
+
+	requests = 0
+	places[5].Side = "Sideways"
+	res, err = client.Batch.PlaceCancelChunked(ctx, []CancelBatch{{OrderID: "c1"}}, places)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "batch order 5") || requests != 0 {
+		t.Errorf("error = %v after %d requests; want order 5 rejected locally", err, requests)
+	}
+	if res.Items["p5"].Status != BatchItemFailed || res.Items["p0"].Status != BatchItemNotSent || res.Items["c1"].Status != BatchItemNotSent {
+		t.Errorf("Items = %+v", res.Items)
+	}
+
+	mux.HandleFunc("/v3/batchorders/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	res, err = client.Batch.CancelOrdersChunked(ctx, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"})
+	if err == nil || res.Items["10"].Status != BatchItemFailed || res.Items["11"].Status != BatchItemNotSent {
+		t.Errorf("error = %v, Items = %+v", err, res.Items)
+	}
+}
+
+func TestGetAndCancelOrdersChunked(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.limiters[EndpointBatch] = rate.NewLimiter(rate.Inf, 1)
+
+	var gets, cancels []int
+	mux.HandleFunc("/v3/batchorders/", func(w http.ResponseWriter, r *http.Request) {
+		ids := strings.Split(strings.TrimPrefix(r.URL.Path, "/v3/batchorders/"), ",")
+		if r.Method == http.MethodGet {
+			gets = append(gets, len(ids))
+			var resp BatchTradeResponse
+			for _, id := range ids {
+				resp.Orders = append(resp.Orders, BatchPlaceData{OrderID: id, Status: OrderStatusPlaced})
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		cancels = append(cancels, len(ids))
+		var resp BatchCancelResponse
+		for _, id := range ids {
+			if id == "5" {
+				resp.UnprocessedRequests = append(resp.UnprocessedRequests, UnprocessedBatchResp{Code: "OrderAlreadyCancelled", RequestID: id})
+				continue
+			}
+			resp.CancelOrders = append(resp.CancelOrders, CancelOrderResp{OrderID: id})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	var ids []string
+	for i := 0; i < 120; i++ {
+		ids = append(ids, fmt.Sprint(i))
+	}
+
+	ctx := context.Background()
+	res, err := client.Batch.GetOrdersChunked(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(gets) != "[50 50 20]" || len(res.Orders) != 120 || res.Items["119"].Status != BatchItemFound {
+		t.Errorf("gets = %v, %d orders", gets, len(res.Orders))
+	}
+
+	res, err = client.Batch.CancelOrdersChunked(ctx, ids[:25])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(cancels) != "[10 10 5]" || len(res.CancelledOrders) != 24 || res.Items["5"].Code != "OrderAlreadyCancelled" {
+		t.Errorf("cancels = %v, result = %+v", cancels, res)
+	}
+
+	if _, err := client.Batch.CancelBatchOrders(ctx, ids[:11]); err == nil {
+		t.Error("CancelBatchOrders should reject more than 10 ids")
+	}
+}
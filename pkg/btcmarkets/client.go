@@ -3,9 +3,6 @@ package btcmarkets
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha512"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -21,14 +18,39 @@ import (
 // BTCMClient is the main struct type representing an interface with the API as a
 // particular client user.
 type BTCMClient struct {
-	apiKey      string
-	privateKey  []byte
-	BaseURL     *url.URL
-	WSURL       *url.URL
-	client      *http.Client
-	UserAgent   string
+	apiKey    string
+	signer    Signer
+	BaseURL   *url.URL
+	WSURL     *url.URL
+	client    *http.Client
+	UserAgent string
+
+	// Ratelimiter is the limiter of the public endpoints, the same as
+	// Limiter(EndpointPublic). Waiting on it shares the budget of the public
+	// market data requests of the client.
+	//
+	// Deprecated: requests are limited per endpoint class; use Limiter and
+	// ClientConfig.RateLimits. Replacing this field has no effect.
 	Ratelimiter *rate.Limiter
 
+	limiters    map[EndpointClass]*rate.Limiter
+	meters      rateMeters
+	retryPolicy *RetryPolicy
+	clock       *clock
+	handler     Handler
+	logger      Logger
+
+	// MarketRules holds the trading rules of every market. Orders are
+	// checked against it before they are sent when enforceMarketRules is set.
+	MarketRules        *MarketRules
+	enforceMarketRules bool
+
+	newClientOrderID    func() string
+	orderLookupAttempts int
+	orderLookupInterval time.Duration
+	orderPollInterval   time.Duration
+	orderWatch          *orderWatchers
+
 	// Services used for communicating with the API
 	// Market MarketService
 	Market         MarketServiceOp
@@ -54,47 +76,127 @@ type ClientConfig struct {
 	WsURL       *url.URL
 	BaseURL     *url.URL
 	RateLimiter *rate.Limiter
+
+	// Environment selects the endpoints of the client; ProductionEnvironment
+	// is used when it is nil. BaseURL, WsURL, APIVersion and WSVersion take
+	// precedence over the environment when set.
+	Environment *Environment
+	APIVersion  string
+	WSVersion   string
+
+	// Signer signs authenticated requests. When nil, an HMACSigner is built
+	// from APISecret; set it to keep the secret out of this process, e.g.
+	// with a UnixSocketSigner.
+	Signer Signer
+
+	// RateLimits overrides the limiter used for an endpoint class. Classes
+	// missing from the map use RateLimiter when it is set, so a single
+	// RateLimiter keeps every call in one shared bucket, or the limits
+	// published by BTCMarkets otherwise; see DefaultRateLimits.
+	RateLimits map[EndpointClass]*rate.Limiter
+
+	// RetryPolicy controls retries of transient failures. DefaultRetryPolicy
+	// is used when it is nil; set MaxRetries to 0 to disable retries.
+	RetryPolicy *RetryPolicy
+
+	// SyncServerTime makes the client measure the offset between the local
+	// clock and the BTCMarkets server clock when it is created, and sign
+	// requests with the corrected time. The offset is measured again once
+	// it is older than ServerTimeRefresh (10 minutes when unset).
+	SyncServerTime    bool
+	ServerTimeRefresh time.Duration
+
+	// Middleware is applied, in order, around the HTTP round trip of every
+	// request made by the client; the first entry is the outermost.
+	Middleware []Middleware
+
+	// Logger receives the diagnostics of the client. Nothing is logged when
+	// it is nil. The API key, the secret and request signatures are
+	// redacted before entries reach it.
+	Logger Logger
+
+	// EnforceMarketRules makes PlaceNewOrder, ReplaceOrder and
+	// BatchPlaceCancelOrders check the amount and prices of orders against
+	// BTCMClient.MarketRules before they are sent.
+	EnforceMarketRules bool
+
+	// ClientOrderIDFunc generates the clientOrderId of orders placed
+	// without one. NewClientOrderID is used when it is nil.
+	ClientOrderIDFunc func() string
+
+	// OrderLookupAttempts is the number of times PlaceNewOrderWithRecovery
+	// looks an order up when its placement outcome is unknown, waiting
+	// OrderLookupInterval between two attempts. They default to 3 and 1s.
+	OrderLookupAttempts int
+	OrderLookupInterval time.Duration
+
+	// OrderPollInterval is how often WaitForOrder fetches an order when no
+	// WebSocket subscription to the orderChange channel is open. It
+	// defaults to 1s.
+	OrderPollInterval time.Duration
 }
 
 func (c ClientConfig) validate() error {
-	if c.APIKey == "" || c.APISecret == "" {
-		return errors.New("Please provide API Key and API Secret")
+	hasSecret := c.APISecret != "" || c.Signer != nil
+	if (c.APIKey == "") != !hasSecret {
+		return errors.New("Please provide both API Key and API Secret, or neither for a public-only client")
 	}
 	return nil
 }
 
-// NewBTCMClient returns a new instance of BTCMarkets Client
+// hasCredentials reports whether the config allows authenticated requests.
+func (c ClientConfig) hasCredentials() bool {
+	return c.APIKey != ""
+}
+
+// NewBTCMClient returns a new instance of BTCMarkets Client. A config without
+// APIKey and APISecret creates a public-only client: market data endpoints
+// and public WebSocket channels work, while authenticated methods fail with
+// ErrNoCredentials.
 func NewBTCMClient(conf ClientConfig) (*BTCMClient, error) {
 	err := conf.validate()
 	if err != nil {
 		return nil, err
 	}
 
-	p, err := base64.StdEncoding.DecodeString(conf.APISecret)
-	if err != nil {
-		return nil, errors.New("Error Decoding APISecret")
+	signer := conf.Signer
+	if signer == nil && conf.hasCredentials() {
+		s, err := NewHMACSigner(conf.APISecret)
+		if err != nil {
+			return nil, err
+		}
+		signer = s
 	}
 
-	rl := rate.NewLimiter(rate.Every(10*time.Second), 50)
-	if conf.RateLimiter != nil {
-		rl = conf.RateLimiter
+	rl := DefaultRateLimits()
+	for _, class := range EndpointClasses {
+		if l, ok := conf.RateLimits[class]; ok && l != nil {
+			rl[class] = l
+		} else if conf.RateLimiter != nil {
+			rl[class] = conf.RateLimiter
+		}
 	}
 
-	baseURL := btcMarketsAPIURL
+	env := ProductionEnvironment()
+	if conf.Environment != nil {
+		env = *conf.Environment
+	}
 	if conf.BaseURL != nil {
-		baseURL = conf.BaseURL.String()
+		env.BaseURL = conf.BaseURL.String()
 	}
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return nil, err
+	if conf.WsURL != nil {
+		env.WsURL = conf.WsURL.String()
 	}
-	u.Path = path.Join(u.Path, btcMarketsAPIVersion) // TODO: Make API Version configurable.
-
-	wss, err := url.Parse(btcMarketsWSURL)
+	if conf.APIVersion != "" {
+		env.APIVersion = conf.APIVersion
+	}
+	if conf.WSVersion != "" {
+		env.WSVersion = conf.WSVersion
+	}
+	u, wss, err := env.endpoints()
 	if err != nil {
 		return nil, err
 	}
-	wss.Path = path.Join(wss.Path, btcMarketsWSVersion) // TODO: Make API Version configurable.
 
 	hc := http.DefaultClient
 	if conf.Httpclient != nil {
@@ -103,26 +205,71 @@ func NewBTCMClient(conf ClientConfig) (*BTCMClient, error) {
 
 	c := &BTCMClient{
 		apiKey:      conf.APIKey,
-		privateKey:  p,
+		signer:      signer,
 		BaseURL:     u,
 		WSURL:       wss,
 		client:      hc,
 		UserAgent:   "mflow/golang-client",
-		Ratelimiter: rl,
+		Ratelimiter: rl[EndpointPublic],
+		limiters:    rl,
+		retryPolicy: conf.RetryPolicy,
+		clock:       &clock{},
+		logger:      newRedactingLogger(conf.Logger, conf.APIKey, conf.APISecret),
+
+		enforceMarketRules: conf.EnforceMarketRules,
+
+		newClientOrderID:    conf.ClientOrderIDFunc,
+		orderLookupAttempts: conf.OrderLookupAttempts,
+		orderLookupInterval: conf.OrderLookupInterval,
+		orderPollInterval:   conf.OrderPollInterval,
+		orderWatch:          &orderWatchers{},
+	}
+	if c.retryPolicy == nil {
+		c.retryPolicy = DefaultRetryPolicy()
 	}
+	if c.newClientOrderID == nil {
+		c.newClientOrderID = NewClientOrderID
+	}
+	if c.orderLookupAttempts <= 0 {
+		c.orderLookupAttempts = defaultOrderLookupAttempts
+	}
+	if c.orderLookupInterval <= 0 {
+		c.orderLookupInterval = defaultOrderLookupInterval
+	}
+	if c.orderPollInterval <= 0 {
+		c.orderPollInterval = defaultOrderPollInterval
+	}
+
+	c.handler = chain(c.send, conf.Middleware)
 
 	c.Market = MarketServiceOp{client: c}
 	c.Order = OrderServiceOp{client: c}
 	c.Batch = BatchOrderServiceOp{client: c}
+	c.Trade = TradeHistoryServiceOp{client: c}
 	c.FundManagement = FundManagementServiceOp{client: c}
 	c.Account = AccountServiceOp{client: c}
 	c.WebSocket = WebSocketServiceOp{client: c}
+	c.MarketRules = newMarketRules(&c.Market)
+
+	if conf.SyncServerTime {
+		c.clock.sync = c.SyncServerTime
+		c.clock.logger = c.logger
+		c.clock.refresh = conf.ServerTimeRefresh
+		if c.clock.refresh <= 0 {
+			c.clock.refresh = defaultServerTimeRefresh
+		}
+		if _, err := c.SyncServerTime(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
 	return c, nil
 }
 
-// NewRequest creates an API request.
-func (c *BTCMClient) NewRequest(method, urlPath string, body interface{}) (*http.Request, error) {
+// NewRequest creates an API request bound to ctx. The context is carried
+// on the returned request and governs the rate limiter wait as well as the
+// HTTP round trip performed by Do and DoAuthenticated.
+func (c *BTCMClient) NewRequest(ctx context.Context, method, urlPath string, body interface{}) (*http.Request, error) {
 	buf := new(bytes.Buffer)
 	rel, err := url.Parse(urlPath)
 	if err != nil {
@@ -140,7 +287,7 @@ func (c *BTCMClient) NewRequest(method, urlPath string, body interface{}) (*http
 	u := c.BaseURL.ResolveReference(rel)
 	u.Path = path.Join(c.BaseURL.Path, rel.Path)
 
-	req, err := http.NewRequest(method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
@@ -159,134 +306,200 @@ func (c *BTCMClient) NewRequest(method, urlPath string, body interface{}) (*http
 
 // Do sends and API request and return the API Response.
 // The response is JSON decoded and store in the value pointed
-// by v, or returns an error
+// by v, or returns an error. The request's context is honoured
+// for the rate limiter wait and the HTTP round trip.
 func (c *BTCMClient) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	// ctx is generated here only to use with Ratelimiter
-	// TODO: Fix performance by removing unneeded allocaton here
-	ctx := context.Background()
-	err := c.Ratelimiter.Wait(ctx) // This is a blocking call.
-	if err != nil {
-		return nil, err
-	}
+	return c.do(req, false, v)
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
+// DoAuthenticated makes API request and return the API Response.
+// The request is signed over its method, path, timestamp and body; data is
+// the payload the request was built with and is only kept for compatibility.
+func (c *BTCMClient) DoAuthenticated(req *http.Request, data, result interface{}) (*http.Response, error) {
+	return c.do(req, true, result)
+}
+
+// do sends req, retrying transient failures according to the client's
+// RetryPolicy. Authenticated requests are signed again on every attempt so
+// that each one carries a fresh timestamp.
+func (c *BTCMClient) do(req *http.Request, authenticated bool, v interface{}) (*http.Response, error) {
+	if authenticated && c.signer == nil {
+		return nil, ErrNoCredentials
 	}
 
-	defer resp.Body.Close()
-	err = CheckResponse(resp)
-	if err != nil {
-		return nil, err
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if v != nil {
-		// err = json.NewDecoder(body).Decode(v)
-		err = json.Unmarshal(body, v)
+	ctx := req.Context()
+	class := c.endpointClass(req, authenticated)
+	idempotent := isIdempotent(req.Method, body)
+	for attempt := 0; ; attempt++ {
+		resp, err := c.roundTrip(req, body, class, authenticated, attempt, v)
+		if err == nil {
+			return resp, nil
+		}
+
+		p := c.retryPolicy
+		if p == nil || attempt >= p.MaxRetries || !idempotent || !isTransient(ctx, err) {
+			return resp, err
+		}
+
+		wait := p.backoff(attempt+1, err)
+		c.logger.Warn("retrying request", "method", req.Method, "path", req.URL.Path,
+			"class", class, "attempt", attempt+1, "wait", wait, "error", err)
+		if p.OnRetry != nil {
+			p.OnRetry(RetryEvent{Request: req, Attempt: attempt + 1, Err: err, Wait: wait})
+		}
+		if t, ok := ctx.Value(retryTraceKey{}).(*retryTrace); ok {
+			t.retries++
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
 	}
-	return resp, err
 }
 
-// DoAuthenticated makes API request and return the API Response
-func (c *BTCMClient) DoAuthenticated(req *http.Request, data, result interface{}) (*http.Response, error) {
-	// ctx is generated here only to use with Ratelimiter
-	// TODO: Fix performance by removing unneeded allocaton here
-	ctx := context.Background()
-	err := c.Ratelimiter.Wait(ctx) // This is a blocking call.
+// roundTrip performs a single attempt of req with the given body.
+func (c *BTCMClient) roundTrip(req *http.Request, body []byte, class EndpointClass, authenticated bool, attempt int, v interface{}) (*http.Response, error) {
+	// Wait on the rate limiter of the endpoint class with the request's
+	// context so that cancellation and deadlines apply while queued.
+	l := c.Limiter(class)
+	err := l.Wait(req.Context()) // This is a blocking call.
 	if err != nil {
 		return nil, err
 	}
+	c.meters.get(l).take(l, time.Now())
 
-	t := strconv.FormatInt(time.Now().UTC().UnixNano()/1000000, 10)
-	p := req.URL.Path
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
 
-	switch data.(type) {
-	case map[string]interface{}, []interface{}:
-		payload, err := json.Marshal(data)
-		strPayload := string(payload)
+	if authenticated {
+		t := strconv.FormatInt(c.clock.timestamp(), 10)
+		m, err := c.signMessage(req.Context(), req.Method+req.URL.Path+t+string(body))
 		if err != nil {
 			return nil, err
 		}
 
-		m := c.signMessage(req.Method + p + t + strPayload)
-		req.Header.Add("BM-AUTH-SIGNATURE", m)
-	default:
-		m := c.signMessage(req.Method + p + t + "")
-		req.Header.Add("BM-AUTH-SIGNATURE", m)
-
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Charset", "UTF-8")
+		req.Header.Set("BM-AUTH-APIKEY", c.apiKey)
+		req.Header.Set("BM-AUTH-TIMESTAMP", t)
+		req.Header.Set("BM-AUTH-SIGNATURE", m)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept-Charset", "UTF-8")
-	req.Header.Add("BM-AUTH-APIKEY", c.apiKey)
-	req.Header.Add("BM-AUTH-TIMESTAMP", t)
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
+	info := &RequestInfo{
+		Class:         class,
+		Method:        req.Method,
+		Authenticated: authenticated,
+		Attempt:       attempt,
 	}
-	defer resp.Body.Close()
-
-	err = CheckResponse(resp)
+	resp, err := c.handler(req, info)
 	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
 		return nil, err
 	}
+	if resp == nil {
+		return nil, errors.New("btcmarkets: middleware returned neither a response nor an error")
+	}
+	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	if result != nil {
-		// err = json.NewDecoder(body).Decode(v)
-		err = json.Unmarshal(body, result)
+	if v != nil {
+		err = json.Unmarshal(data, v)
 	}
 
 	return resp, err
 }
 
-// An ErrorResponse reports the error caused by an API request
-// If response is 200 but there is marshalling error there could
-// be a issue with Unmarshaling the data into the struct
+// An ErrorResponse reports the error caused by an API request.
+// BTCMarkets returns errors as a JSON body of the form
+// {"code": "InvalidPrice", "message": "..."}; both attributes are decoded
+// along with the HTTP status code. An ErrorResponse matches one of the
+// package sentinel errors (ErrValidation, ErrRateLimited, ...) through
+// errors.Is.
 type ErrorResponse struct {
 	// HTTP response that caused this error
-	Response *http.Response
+	Response *http.Response `json:"-"`
+
+	// HTTP status code of the response
+	StatusCode int `json:"-"`
 
-	// Error message - maybe the json for this is "fault"
+	// Machine readable error code e.g. InsufficientFund, InvalidPrice
+	Code string `json:"code"`
+
+	// Human readable error message
 	Message string `json:"message"`
 }
 
 // Error method to satisfy the Error Interface requirement
 func (e ErrorResponse) Error() string {
-	return e.Message
+	if e.Code == "" {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is reports whether target is the sentinel error for the category of e.
+func (e ErrorResponse) Is(target error) bool {
+	return errorCategory(e.StatusCode, e.Code) == target
 }
 
 // CheckResponse checks the API response for errors, and returns them if
 // present. A response is considered an error if it has a status code outside
-// the 200 range. API error responses are expected to have either no response
-// body, or a JSON response body that maps to ErrorResponse. Any other response
-// body will be silently ignored.
+// the 200 range. The request kept by the response is replaced with a copy
+// whose BM-AUTH-SIGNATURE and BM-AUTH-APIKEY headers are redacted. API error
+// responses are expected to have either no response body, or a JSON response
+// body that maps to ErrorResponse. Any other response body is kept verbatim
+// in ErrorResponse.Message.
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; c >= 200 && c <= 299 {
 		return nil
 	}
 
-	errorResponse := &ErrorResponse{Response: r}
+	// Keep the request for inspection without its credentials.
+	if r.Request != nil {
+		req := *r.Request
+		req.Header = redactHeader(r.Request.Header)
+		r.Request = &req
+	}
+
+	errorResponse := &ErrorResponse{Response: r, StatusCode: r.StatusCode}
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		errorResponse.Message = err.Error()
 		return errorResponse
 	}
 
-	errorResponse.Message = string(data)
+	if err := json.Unmarshal(data, errorResponse); err != nil || (errorResponse.Code == "" && errorResponse.Message == "") {
+		errorResponse.Code = ""
+		errorResponse.Message = string(data)
+	}
+	if errorResponse.Message == "" && errorResponse.Code == "" {
+		errorResponse.Message = http.StatusText(r.StatusCode)
+	}
 	return errorResponse
 }
 
 // GetServerTime get the BTCMarkets server time
 // This is required to build a valid authenticated request
-func (c *BTCMClient) GetServerTime() (ServerTime, error) {
+func (c *BTCMClient) GetServerTime(ctx context.Context) (ServerTime, error) {
 	t := ServerTime{}
-	req, err := c.NewRequest(http.MethodGet, btcMarketsGetTime, nil)
+	req, err := c.NewRequest(ctx, http.MethodGet, btcMarketsGetTime, nil)
 	if err != nil {
 		return t, err
 	}
@@ -298,10 +511,8 @@ func (c *BTCMClient) GetServerTime() (ServerTime, error) {
 	return t, nil
 }
 
-// singMessage takes a string - generally a path/URI to Sign and
-// returns a base64 encoded string
-func (c *BTCMClient) signMessage(s string) string {
-	h := hmac.New(sha512.New, c.privateKey)
-	h.Write([]byte(s))
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+// signMessage takes a string - generally a path/URI to Sign and
+// returns a base64 encoded signature made by the client's Signer
+func (c *BTCMClient) signMessage(ctx context.Context, s string) (string, error) {
+	return c.signer.Sign(ctx, s)
 }
@@ -1,6 +1,8 @@
 package btcmarkets
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -31,6 +33,8 @@ func setup(r *rate.Limiter) (*BTCMClient, *http.ServeMux, *httptest.Server, func
 		APISecret:   "TXlTdXBlclNlY3JldEtleQ==",
 		Httpclient:  ts.Client(),
 		RateLimiter: rl,
+		// Retry like DefaultRetryPolicy, without slowing tests down.
+		RetryPolicy: &RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
 	}
 
 	client, err := NewBTCMClient(conf)
@@ -69,7 +73,10 @@ func TestSignMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := client.signMessage(tt.msg + tm)
+			got, err := client.signMessage(context.Background(), tt.msg+tm)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if tt.want != got {
 				t.Errorf("client.singMessage() wanted = '%v' got = '%v' ", tt.want, got)
 			}
@@ -94,10 +101,76 @@ func TestGetServerTime(t *testing.T) {
 	}
 
 	mux.HandleFunc("/v3/time", mocktimeserver)
-	st, err := client.GetServerTime()
+	st, err := client.GetServerTime(context.Background())
 	ty := reflect.TypeOf(st).String()
 	if ty != "btcmarkets.ServerTime" {
 		t.Errorf("Expected btcmarkets.ServerTime got %s", ty)
 	}
 
 }
+
+func TestRequestContextCancelled(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := false
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.GetServerTime(ctx)
+	if err == nil {
+		t.Error("Expected an error for a cancelled context")
+	}
+	if called {
+		t.Error("Expected the request not to reach the server")
+	}
+}
+
+func TestPublicOnlyClient(t *testing.T) {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	called := false
+	mux.HandleFunc("/v3/markets", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"marketId":"BTC-AUD","baseAssetName":"BTC","quoteAssetName":"AUD"}]`))
+	})
+	mux.HandleFunc("/v3/accounts/me/balances", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	b, _ := url.Parse(ts.URL)
+	client, err := NewBTCMClient(ClientConfig{BaseURL: b, Httpclient: ts.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := client.Market.AllMarkets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].MarketID != "BTC-AUD" {
+		t.Errorf("Unexpected markets %+v", m)
+	}
+
+	_, err = client.Account.GetBalances(context.Background())
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials got %v", err)
+	}
+	if called {
+		t.Error("Expected no network call for an authenticated method")
+	}
+
+	_, err = NewBTCMClient(ClientConfig{APIKey: "25d55ef7-f33e-49e8"})
+	if err == nil {
+		t.Error("Expected an error for an API key without a secret")
+	}
+}
@@ -0,0 +1,138 @@
+package btcmarkets
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultServerTimeRefresh is how often the server clock offset is measured
+// again when ClientConfig.SyncServerTime is set without a refresh interval.
+const defaultServerTimeRefresh = 10 * time.Minute
+
+// serverTimeRetry is the wait before measuring the offset again after a
+// failed refresh. It doubles after every consecutive failure, up to the
+// refresh interval.
+const serverTimeRetry = 5 * time.Second
+
+// clock produces the millisecond timestamps used to sign requests. It
+// corrects the local time with the offset measured against the BTCMarkets
+// server and never issues the same timestamp twice, even to goroutines
+// signing concurrently within the same millisecond.
+type clock struct {
+	offset  int64 // nanoseconds to add to the local time, accessed atomically
+	last    int64 // last issued timestamp in milliseconds, accessed atomically
+	next    int64 // local unix nanoseconds of the next refresh, accessed atomically
+	syncing int32 // set while a background sync is in flight
+
+	// failures counts the consecutive failed refreshes. It is only used by
+	// the background sync, which never runs twice at once.
+	failures uint
+
+	// refresh is the maximum age of the offset before it is measured again.
+	// A zero value disables refreshing.
+	refresh time.Duration
+	sync    func(context.Context) (time.Duration, error)
+	logger  Logger
+}
+
+// now returns the local time corrected with the server clock offset.
+func (c *clock) now() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&c.offset)))
+}
+
+// timestamp returns a strictly increasing timestamp in milliseconds.
+func (c *clock) timestamp() int64 {
+	c.maybeRefresh()
+
+	ms := c.now().UnixNano() / int64(time.Millisecond)
+	for {
+		last := atomic.LoadInt64(&c.last)
+		next := ms
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&c.last, last, next) {
+			return next
+		}
+	}
+}
+
+// setOffset records a freshly measured server clock offset.
+func (c *clock) setOffset(d time.Duration) {
+	atomic.StoreInt64(&c.offset, int64(d))
+	atomic.StoreInt64(&c.next, time.Now().Add(c.refresh).UnixNano())
+}
+
+// maybeRefresh measures the offset again in the background once it is older
+// than the refresh interval. Signing never waits for the refresh. A failed
+// refresh is logged and retried after a backoff, while the last offset
+// keeps being used.
+func (c *clock) maybeRefresh() {
+	if c.refresh <= 0 || c.sync == nil {
+		return
+	}
+	if time.Now().UnixNano() < atomic.LoadInt64(&c.next) {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&c.syncing, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&c.syncing, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := c.sync(ctx); err != nil {
+			wait := c.retryWait()
+			atomic.StoreInt64(&c.next, time.Now().Add(wait).UnixNano())
+			if c.logger != nil {
+				c.logger.Warn("server time sync failed", "retryIn", wait, "error", err)
+			}
+			return
+		}
+		c.failures = 0
+	}()
+}
+
+// retryWait records a failed refresh and returns the wait before the next
+// one.
+func (c *clock) retryWait() time.Duration {
+	c.failures++
+	wait := serverTimeRetry
+	for i := uint(1); i < c.failures && wait < c.refresh; i++ {
+		wait *= 2
+	}
+	if wait > c.refresh {
+		wait = c.refresh
+	}
+	return wait
+}
+
+// SyncServerTime measures the offset between the local clock and the
+// BTCMarkets server clock using /v3/time and uses it for every signature
+// made from now on. The round trip time is split evenly to estimate when
+// the server read its clock. It returns the measured offset.
+func (c *BTCMClient) SyncServerTime(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	st, err := c.GetServerTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	end := time.Now()
+
+	server, err := time.Parse(time.RFC3339Nano, st.Timestamp)
+	if err != nil {
+		return 0, err
+	}
+
+	offset := server.Sub(start.Add(end.Sub(start) / 2))
+	c.clock.setOffset(offset)
+	return offset, nil
+}
+
+// ServerTimeOffset returns the offset between the BTCMarkets server clock and
+// the local clock last measured by SyncServerTime.
+func (c *BTCMClient) ServerTimeOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.clock.offset))
+}
@@ -0,0 +1,110 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClockTimestampStrictlyIncreasing(t *testing.T) {
+	c := &clock{}
+
+	const workers, n = 8, 500
+	var mu sync.Mutex
+	seen := make(map[int64]bool, workers*n)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prev := int64(0)
+			for i := 0; i < n; i++ {
+				ts := c.timestamp()
+				if ts <= prev {
+					t.Errorf("Timestamp %d not greater than previous %d", ts, prev)
+				}
+				prev = ts
+				mu.Lock()
+				if seen[ts] {
+					t.Errorf("Timestamp %d issued twice", ts)
+				}
+				seen[ts] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSyncServerTime(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		ahead := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+		w.Write([]byte(`{"timestamp": "` + ahead + `"}`))
+	})
+
+	offset, err := client.SyncServerTime(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < 59*time.Minute || offset > 61*time.Minute {
+		t.Errorf("Expected an offset of about 1h got %v", offset)
+	}
+
+	want := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	if ts := client.clock.timestamp(); ts < want-int64(time.Minute/time.Millisecond) {
+		t.Errorf("Expected the timestamp to be corrected, got %d want about %d", ts, want)
+	}
+}
+
+func TestClockRefreshBacksOff(t *testing.T) {
+	var syncs, warnings int32
+	c := &clock{
+		refresh: time.Minute,
+		sync: func(context.Context) (time.Duration, error) {
+			atomic.AddInt32(&syncs, 1)
+			return 0, errors.New("unavailable")
+		},
+		logger: LogFunc(func(level LogLevel, msg string, keysAndValues ...interface{}) {
+			if level == LevelWarn {
+				atomic.AddInt32(&warnings, 1)
+			}
+		}),
+	}
+
+	c.timestamp()
+	for atomic.LoadInt32(&c.syncing) == 1 || atomic.LoadInt32(&syncs) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		c.timestamp()
+	}
+	for atomic.LoadInt32(&c.syncing) == 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&syncs); n != 1 {
+		t.Errorf("syncs = %d; want 1 until the backoff elapses", n)
+	}
+	if n := atomic.LoadInt32(&warnings); n != 1 {
+		t.Errorf("warnings = %d; want 1", n)
+	}
+	if next := time.Until(time.Unix(0, atomic.LoadInt64(&c.next))); next < 4*time.Second || next > serverTimeRetry {
+		t.Errorf("next refresh in %v; want about %v", next, serverTimeRetry)
+	}
+
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := c.retryWait(); got != want {
+			t.Errorf("retryWait = %v; want %v", got, want)
+		}
+	}
+}
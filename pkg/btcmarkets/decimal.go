@@ -0,0 +1,365 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number used for every price, amount, fee and
+// balance exchanged with BTCMarkets. Its value is unscaled × 10^-scale, so
+// satoshi-level amounts are represented without rounding artefacts. The
+// zero value is 0 and Decimal values are immutable: arithmetic methods
+// return a new Decimal.
+//
+// Decimal is encoded in JSON as a string, the way BTCMarkets sends and
+// expects prices and amounts, and keeps the digits it was decoded from so
+// values round-trip exactly.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// maxDecimalScale bounds the scale, positive or negative, of a parsed
+// Decimal, so input such as "1e-999999999" cannot make ParseDecimal and the
+// arithmetic on its result allocate huge numbers.
+const maxDecimalScale = 1000
+
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// pow10 returns 10^n for n >= 0.
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// NewDecimal returns unscaled × 10^-scale.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return newDecimal(big.NewInt(unscaled), scale)
+}
+
+// NewDecimalFromInt returns the Decimal of an integer.
+func NewDecimalFromInt(i int64) Decimal {
+	return NewDecimal(i, 0)
+}
+
+// NewDecimalFromFloat returns the Decimal of the shortest decimal
+// representation of f. It fails for NaN and infinite values.
+func NewDecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("btcmarkets: cannot convert %v to a decimal", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// ParseDecimal parses a decimal number such as "-12.3400" or "1e-8". It
+// fails for numbers with more than 1000 decimals or above 1e1000.
+func ParseDecimal(s string) (Decimal, error) {
+	invalid := fmt.Errorf("btcmarkets: invalid decimal %q", s)
+	str := strings.TrimSpace(s)
+
+	exp := int64(0)
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(str[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		exp = e
+		str = str[:i]
+	}
+
+	neg := false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	intPart, frac := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, frac = str[:i], str[i+1:]
+	}
+	digits := intPart + frac
+	if digits == "" {
+		return Decimal{}, invalid
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Decimal{}, invalid
+		}
+	}
+
+	v, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, invalid
+	}
+	if neg {
+		v.Neg(v)
+	}
+
+	scale := int64(len(frac)) - exp
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("btcmarkets: decimal %q is out of range", s)
+	}
+	return newDecimal(v, int32(scale)), nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s is not a valid
+// decimal. It simplifies declaring constants.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// newDecimal returns v × 10^-scale, keeping the scale non negative.
+func newDecimal(v *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		v = new(big.Int).Mul(v, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled: v, scale: scale}
+}
+
+// bigInt returns the unscaled value of d.
+func (d Decimal) bigInt() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value of d at the given scale, which must
+// not be lower than the scale of d.
+func (d Decimal) rescale(scale int32) *big.Int {
+	v := new(big.Int).Set(d.bigInt())
+	if scale > d.scale {
+		v.Mul(v, pow10(scale-d.scale))
+	}
+	return v
+}
+
+// commonScale returns the larger scale of d and o.
+func (d Decimal) commonScale(o Decimal) int32 {
+	if o.scale > d.scale {
+		return o.scale
+	}
+	return d.scale
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// String returns d in plain decimal notation, keeping its scale.
+func (d Decimal) String() string {
+	v := d.bigInt()
+	s := new(big.Int).Abs(v).String()
+	if d.scale > 0 {
+		if len(s) <= int(d.scale) {
+			s = strings.Repeat("0", int(d.scale)-len(s)+1) + s
+		}
+		p := len(s) - int(d.scale)
+		s = s[:p] + "." + s[p:]
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Float64 returns the nearest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	s := d.commonScale(o)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(s), o.rescale(s)), scale: s}
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	s := d.commonScale(o)
+	return Decimal{unscaled: new(big.Int).Sub(d.rescale(s), o.rescale(s)), scale: s}
+}
+
+// Mul returns d × o.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.bigInt(), o.bigInt()), scale: d.scale + o.scale}
+}
+
+// Div returns d / o rounded half away from zero to the given number of
+// decimal places. It panics if o is zero.
+func (d Decimal) Div(o Decimal, places int32) Decimal {
+	if o.IsZero() {
+		panic(errors.New("btcmarkets: decimal division by zero"))
+	}
+	if places < 0 {
+		places = 0
+	}
+
+	num := new(big.Int).Mul(d.bigInt(), pow10(o.scale+places))
+	den := new(big.Int).Mul(o.bigInt(), pow10(d.scale))
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, bigOne)
+		} else {
+			q.Add(q, bigOne)
+		}
+	}
+	return Decimal{unscaled: q, scale: places}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.bigInt()), scale: d.scale}
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	return Decimal{unscaled: new(big.Int).Abs(d.bigInt()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.bigInt().Sign()
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and o and returns -1, 0 or 1 when d is lower than, equal
+// to or greater than o.
+func (d Decimal) Cmp(o Decimal) int {
+	s := d.commonScale(o)
+	return d.rescale(s).Cmp(o.rescale(s))
+}
+
+// Equal reports whether d and o have the same value, regardless of scale.
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+// LessThan reports whether d < o.
+func (d Decimal) LessThan(o Decimal) bool {
+	return d.Cmp(o) < 0
+}
+
+// GreaterThan reports whether d > o.
+func (d Decimal) GreaterThan(o Decimal) bool {
+	return d.Cmp(o) > 0
+}
+
+type roundingMode int
+
+const (
+	roundDown     roundingMode = iota // toward zero
+	roundFloor                        // toward negative infinity
+	roundCeil                         // toward positive infinity
+	roundHalfAway                     // to nearest, ties away from zero
+)
+
+// round returns d with at most places decimal places using mode.
+func (d Decimal) round(places int32, mode roundingMode) Decimal {
+	if places >= d.scale {
+		return d
+	}
+
+	div := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.bigInt(), div, new(big.Int))
+	switch mode {
+	case roundFloor:
+		if r.Sign() < 0 {
+			q.Sub(q, bigOne)
+		}
+	case roundCeil:
+		if r.Sign() > 0 {
+			q.Add(q, bigOne)
+		}
+	case roundHalfAway:
+		r.Abs(r).Lsh(r, 1)
+		if r.Cmp(div) >= 0 {
+			if d.Sign() < 0 {
+				q.Sub(q, bigOne)
+			} else {
+				q.Add(q, bigOne)
+			}
+		}
+	}
+	return newDecimal(q, places)
+}
+
+// Truncate returns d with at most places decimal places, dropping the
+// remaining digits.
+func (d Decimal) Truncate(places int32) Decimal {
+	return d.round(places, roundDown)
+}
+
+// Floor returns d rounded toward negative infinity to places decimal places.
+func (d Decimal) Floor(places int32) Decimal {
+	return d.round(places, roundFloor)
+}
+
+// Ceil returns d rounded toward positive infinity to places decimal places.
+func (d Decimal) Ceil(places int32) Decimal {
+	return d.round(places, roundCeil)
+}
+
+// Round returns d rounded half away from zero to places decimal places.
+func (d Decimal) Round(places int32) Decimal {
+	return d.round(places, roundHalfAway)
+}
+
+// MarshalJSON encodes d as a JSON string.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON decodes d from a JSON string or number. An empty string
+// decodes as 0 and null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// MarshalText encodes d in plain decimal notation.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes d from its decimal notation. An empty text decodes
+// as 0.
+func (d *Decimal) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
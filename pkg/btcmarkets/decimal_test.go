@@ -0,0 +1,122 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "0", want: "0"},
+		{in: "12.3400", want: "12.3400"},
+		{in: "-0.00000001", want: "-0.00000001"},
+		{in: ".5", want: "0.5"},
+		{in: "+7", want: "7"},
+		{in: "1e-8", want: "0.00000001"},
+		{in: "1.5E3", want: "1500"},
+		{in: "", err: true},
+		{in: "abc", err: true},
+		{in: "1.2.3", err: true},
+		{in: "1e-1000", want: "0." + strings.Repeat("0", 999) + "1"},
+		{in: "1e-999999999", err: true},
+		{in: "1e2147483647", err: true},
+		{in: "0." + strings.Repeat("0", 1000) + "1", err: true},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q) expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q) unexpected error %v", tt.in, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustParseDecimal("0.1")
+	b := MustParseDecimal("0.2")
+
+	if got := a.Add(b); !got.Equal(MustParseDecimal("0.3")) {
+		t.Errorf("0.1 + 0.2 = %s", got)
+	}
+	if got := a.Sub(b); got.String() != "-0.1" {
+		t.Errorf("0.1 - 0.2 = %s", got)
+	}
+	if got := MustParseDecimal("1.5").Mul(MustParseDecimal("0.00000002")); got.String() != "0.000000030" {
+		t.Errorf("1.5 * 0.00000002 = %s", got)
+	}
+	if got := NewDecimalFromInt(2).Div(NewDecimalFromInt(3), 8); got.String() != "0.66666667" {
+		t.Errorf("2 / 3 = %s", got)
+	}
+	if got := NewDecimalFromInt(-2).Div(NewDecimalFromInt(3), 2); got.String() != "-0.67" {
+		t.Errorf("-2 / 3 = %s", got)
+	}
+	if !a.LessThan(b) || b.Cmp(a) != 1 || !MustParseDecimal("1.00").Equal(NewDecimalFromInt(1)) {
+		t.Error("Unexpected comparison result")
+	}
+	if !(Decimal{}).IsZero() || (Decimal{}).String() != "0" {
+		t.Error("Expected the zero value to be 0")
+	}
+}
+
+func TestDecimalRounding(t *testing.T) {
+	d := MustParseDecimal("-1.2345")
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"Truncate", d.Truncate(2), "-1.23"},
+		{"Floor", d.Floor(2), "-1.24"},
+		{"Ceil", d.Ceil(2), "-1.23"},
+		{"Round", d.Round(3), "-1.235"},
+		{"Round up", MustParseDecimal("2.5").Round(0), "3"},
+		{"Floor positive", MustParseDecimal("0.123456789").Floor(8), "0.12345678"},
+		{"No rounding needed", MustParseDecimal("1.5").Floor(4), "1.5"},
+		{"Negative places", MustParseDecimal("1234").Floor(-2), "1200"},
+	}
+
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s: got %s want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		Price  Decimal `json:"price"`
+		Amount Decimal `json:"amount"`
+		Fee    Decimal `json:"fee"`
+	}
+
+	in := `{"price":"12345.67000000","amount":0.00000001,"fee":""}`
+	if err := json.Unmarshal([]byte(in), &v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"price":"12345.67000000","amount":"0.00000001","fee":"0"}` {
+		t.Errorf("Unexpected JSON %s", out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"price":"1,2"}`), &v); err == nil {
+		t.Error("Expected an error for an invalid decimal")
+	}
+}
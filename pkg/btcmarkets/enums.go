@@ -0,0 +1,273 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Side is the side of an order or trade.
+type Side string
+
+// Order sides
+const (
+	SideBid Side = "Bid"
+	SideAsk Side = "Ask"
+)
+
+// OrderType is the type of an order.
+type OrderType string
+
+// Order types
+const (
+	OrderTypeLimit      OrderType = "Limit"
+	OrderTypeMarket     OrderType = "Market"
+	OrderTypeStopLimit  OrderType = "Stop Limit"
+	OrderTypeStop       OrderType = "Stop"
+	OrderTypeTakeProfit OrderType = "Take Profit"
+)
+
+// OrderStatus is the status of an order in its life cycle.
+type OrderStatus string
+
+// Order statuses
+const (
+	OrderStatusAccepted           OrderStatus = "Accepted"
+	OrderStatusPlaced             OrderStatus = "Placed"
+	OrderStatusPartiallyMatched   OrderStatus = "Partially Matched"
+	OrderStatusFullyMatched       OrderStatus = "Fully Matched"
+	OrderStatusCancelled          OrderStatus = "Cancelled"
+	OrderStatusPartiallyCancelled OrderStatus = "Partially Cancelled"
+	OrderStatusFailed             OrderStatus = "Failed"
+)
+
+// TimeInForce controls how long an order stays in the orderbook.
+type TimeInForce string
+
+// Time in force values
+const (
+	// TimeInForceGTC keeps the order until it is matched or cancelled
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceIOC matches what it can immediately and cancels the rest
+	TimeInForceIOC TimeInForce = "IOC"
+	// TimeInForceFOK matches the whole order immediately or cancels it
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+// SelfTrade controls whether an order may match another order of the same
+// account.
+type SelfTrade string
+
+// Self trade values
+const (
+	SelfTradeAllow   SelfTrade = "A"
+	SelfTradePrevent SelfTrade = "P"
+)
+
+var (
+	sides         = []string{string(SideBid), string(SideAsk)}
+	orderTypes    = []string{string(OrderTypeLimit), string(OrderTypeMarket), string(OrderTypeStopLimit), string(OrderTypeStop), string(OrderTypeTakeProfit)}
+	orderStatuses = []string{string(OrderStatusAccepted), string(OrderStatusPlaced), string(OrderStatusPartiallyMatched), string(OrderStatusFullyMatched),
+		string(OrderStatusCancelled), string(OrderStatusPartiallyCancelled), string(OrderStatusFailed)}
+	timeInForces = []string{string(TimeInForceGTC), string(TimeInForceIOC), string(TimeInForceFOK)}
+	selfTrades   = []string{string(SelfTradeAllow), string(SelfTradePrevent)}
+)
+
+// parseEnum returns the canonical spelling of v among values, ignoring case.
+func parseEnum(kind, v string, values []string) (string, error) {
+	for _, c := range values {
+		if strings.EqualFold(c, v) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("btcmarkets: invalid %s %q: %w", kind, v, ErrValidation)
+}
+
+// unmarshalEnum decodes a JSON string into the canonical spelling of one of
+// values. Other strings are decoded as is, so a value added by the exchange
+// does not make whole responses fail to decode.
+func unmarshalEnum(b []byte, values []string) (string, error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return "", err
+	}
+	for _, c := range values {
+		if strings.EqualFold(c, s) {
+			return c, nil
+		}
+	}
+	return s, nil
+}
+
+// marshalEnum encodes v as a JSON string, failing if it is not one of values.
+// An unset value is encoded as an empty string.
+func marshalEnum(kind, v string, values []string) ([]byte, error) {
+	if v != "" {
+		if _, err := parseEnum(kind, v, values); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(v)
+}
+
+// ParseSide parses s, ignoring case.
+func ParseSide(s string) (Side, error) {
+	v, err := parseEnum("side", s, sides)
+	return Side(v), err
+}
+
+// Validate returns an error if s is not a known side.
+func (s Side) Validate() error {
+	_, err := ParseSide(string(s))
+	return err
+}
+
+// Opposite returns the other side.
+func (s Side) Opposite() Side {
+	if s == SideBid {
+		return SideAsk
+	}
+	return SideBid
+}
+
+// MarshalJSON encodes s, failing if it is not a known side.
+func (s Side) MarshalJSON() ([]byte, error) {
+	return marshalEnum("side", string(s), sides)
+}
+
+// UnmarshalJSON decodes s, ignoring case. Unknown values are kept as
+// sent and rejected by Validate.
+func (s *Side) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, sides)
+	*s = Side(v)
+	return err
+}
+
+// ParseOrderType parses s, ignoring case.
+func ParseOrderType(s string) (OrderType, error) {
+	v, err := parseEnum("order type", s, orderTypes)
+	return OrderType(v), err
+}
+
+// Validate returns an error if t is not a known order type.
+func (t OrderType) Validate() error {
+	_, err := ParseOrderType(string(t))
+	return err
+}
+
+// RequiresTriggerPrice reports whether orders of type t need a trigger price.
+func (t OrderType) RequiresTriggerPrice() bool {
+	return t == OrderTypeStopLimit || t == OrderTypeStop || t == OrderTypeTakeProfit
+}
+
+// RequiresPrice reports whether orders of type t need a limit price.
+func (t OrderType) RequiresPrice() bool {
+	return t == OrderTypeLimit || t == OrderTypeStopLimit
+}
+
+// MarshalJSON encodes t, failing if it is not a known order type.
+func (t OrderType) MarshalJSON() ([]byte, error) {
+	return marshalEnum("order type", string(t), orderTypes)
+}
+
+// UnmarshalJSON decodes t, ignoring case. Unknown values are kept as
+// sent and rejected by Validate.
+func (t *OrderType) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, orderTypes)
+	*t = OrderType(v)
+	return err
+}
+
+// ParseOrderStatus parses s, ignoring case.
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	v, err := parseEnum("order status", s, orderStatuses)
+	return OrderStatus(v), err
+}
+
+// Validate returns an error if s is not a known order status.
+func (s OrderStatus) Validate() error {
+	_, err := ParseOrderStatus(string(s))
+	return err
+}
+
+// IsTerminal reports whether an order with status s can no longer change:
+// Fully Matched, Cancelled, Partially Cancelled or Failed.
+func (s OrderStatus) IsTerminal() bool {
+	switch s {
+	case OrderStatusFullyMatched, OrderStatusCancelled, OrderStatusPartiallyCancelled, OrderStatusFailed:
+		return true
+	}
+	return false
+}
+
+// IsOpen reports whether an order with status s can still be matched.
+func (s OrderStatus) IsOpen() bool {
+	switch s {
+	case OrderStatusAccepted, OrderStatusPlaced, OrderStatusPartiallyMatched:
+		return true
+	}
+	return false
+}
+
+// MarshalJSON encodes s, failing if it is not a known order status.
+func (s OrderStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum("order status", string(s), orderStatuses)
+}
+
+// UnmarshalJSON decodes s, ignoring case. Unknown values are kept as
+// sent and rejected by Validate.
+func (s *OrderStatus) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, orderStatuses)
+	*s = OrderStatus(v)
+	return err
+}
+
+// ParseTimeInForce parses s, ignoring case.
+func ParseTimeInForce(s string) (TimeInForce, error) {
+	v, err := parseEnum("time in force", s, timeInForces)
+	return TimeInForce(v), err
+}
+
+// Validate returns an error if t is not a known time in force.
+func (t TimeInForce) Validate() error {
+	_, err := ParseTimeInForce(string(t))
+	return err
+}
+
+// MarshalJSON encodes t, failing if it is not a known time in force.
+func (t TimeInForce) MarshalJSON() ([]byte, error) {
+	return marshalEnum("time in force", string(t), timeInForces)
+}
+
+// UnmarshalJSON decodes t, ignoring case. Unknown values are kept as
+// sent and rejected by Validate.
+func (t *TimeInForce) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, timeInForces)
+	*t = TimeInForce(v)
+	return err
+}
+
+// ParseSelfTrade parses s, ignoring case.
+func ParseSelfTrade(s string) (SelfTrade, error) {
+	v, err := parseEnum("self trade", s, selfTrades)
+	return SelfTrade(v), err
+}
+
+// Validate returns an error if s is not a known self trade value.
+func (s SelfTrade) Validate() error {
+	_, err := ParseSelfTrade(string(s))
+	return err
+}
+
+// MarshalJSON encodes s, failing if it is not a known self trade value.
+func (s SelfTrade) MarshalJSON() ([]byte, error) {
+	return marshalEnum("self trade", string(s), selfTrades)
+}
+
+// UnmarshalJSON decodes s, ignoring case. Unknown values are kept as
+// sent and rejected by Validate.
+func (s *SelfTrade) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, selfTrades)
+	*s = SelfTrade(v)
+	return err
+}
@@ -0,0 +1,117 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseEnums(t *testing.T) {
+	side, err := ParseSide("bid")
+	if err != nil || side != SideBid {
+		t.Errorf("ParseSide(bid) = %q, %v; want %q", side, err, SideBid)
+	}
+
+	ot, err := ParseOrderType("stop limit")
+	if err != nil || ot != OrderTypeStopLimit {
+		t.Errorf("ParseOrderType(stop limit) = %q, %v; want %q", ot, err, OrderTypeStopLimit)
+	}
+
+	st, err := ParseOrderStatus("PARTIALLY MATCHED")
+	if err != nil || st != OrderStatusPartiallyMatched {
+		t.Errorf("ParseOrderStatus(PARTIALLY MATCHED) = %q, %v; want %q", st, err, OrderStatusPartiallyMatched)
+	}
+
+	if _, err := ParseTimeInForce("GTD"); !errors.Is(err, ErrValidation) {
+		t.Errorf("ParseTimeInForce(GTD) error = %v; want ErrValidation", err)
+	}
+	if _, err := ParseSelfTrade("x"); !errors.Is(err, ErrValidation) {
+		t.Errorf("ParseSelfTrade(x) error = %v; want ErrValidation", err)
+	}
+}
+
+func TestEnumJSON(t *testing.T) {
+	var o OrderData
+	err := json.Unmarshal([]byte(`{"side":"ask","type":"take profit","status":"Fully Matched"}`), &o)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if o.Side != SideAsk || o.Type != OrderTypeTakeProfit || o.Status != OrderStatusFullyMatched {
+		t.Errorf("Unmarshal = %q %q %q", o.Side, o.Type, o.Status)
+	}
+
+	// Values unknown to the package are kept so responses still decode.
+	if err := json.Unmarshal([]byte(`{"side":"Buy","type":"Iceberg","status":"Expired"}`), &o); err != nil {
+		t.Fatalf("Unmarshal unknown values error: %v", err)
+	}
+	if o.Side != "Buy" || o.Type != "Iceberg" || o.Status != "Expired" {
+		t.Errorf("Unmarshal unknown values = %q %q %q", o.Side, o.Type, o.Status)
+	}
+	if err := o.Side.Validate(); !errors.Is(err, ErrValidation) {
+		t.Errorf("Validate unknown side error = %v; want ErrValidation", err)
+	}
+	if e, err := decodeWSEvent([]byte(`{"orderId":1,"status":"Expired","messageType":"orderChange"}`)); err != nil {
+		t.Errorf("decodeWSEvent unknown status error: %v", err)
+	} else if oc, ok := e.(*BTCMWSOrderChangeEvent); !ok || oc.Status != "Expired" {
+		t.Errorf("decodeWSEvent = %#v", e)
+	}
+
+	b, err := json.Marshal(PlaceBatch{MarketID: "BTC-AUD", OrderType: OrderTypeMarket, Side: SideBid})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var m map[string]interface{}
+	json.Unmarshal(b, &m)
+	if m["type"] != "Market" || m["side"] != "Bid" {
+		t.Errorf("Marshal = %s", b)
+	}
+	if _, ok := m["timeInForce"]; ok {
+		t.Errorf("Marshal = %s; want timeInForce omitted", b)
+	}
+	if _, ok := m["price"]; ok {
+		t.Errorf("Marshal = %s; want price omitted", b)
+	}
+
+	if _, err := json.Marshal(PlaceBatch{OrderType: "Iceberg", Side: SideBid}); err == nil {
+		t.Error("Marshal unknown order type: expected error")
+	}
+}
+
+func TestOrderStatusIsTerminal(t *testing.T) {
+	terminal := map[OrderStatus]bool{
+		OrderStatusAccepted:           false,
+		OrderStatusPlaced:             false,
+		OrderStatusPartiallyMatched:   false,
+		OrderStatusFullyMatched:       true,
+		OrderStatusCancelled:          true,
+		OrderStatusPartiallyCancelled: true,
+		OrderStatusFailed:             true,
+	}
+	for s, want := range terminal {
+		if got := s.IsTerminal(); got != want {
+			t.Errorf("%q.IsTerminal() = %v; want %v", s, got, want)
+		}
+		if got := s.IsOpen(); got == want {
+			t.Errorf("%q.IsOpen() = %v; want %v", s, got, !want)
+		}
+	}
+}
+
+func TestPlaceNewOrderRejectsInvalidEnums(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not be sent")
+	})
+
+	_, err = client.Order.PlaceNewOrder(context.Background(), NewLimitOrder("BTC-AUD", "Buy", MustParseDecimal("1"), MustParseDecimal("1")))
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("PlaceNewOrder error = %v; want ErrValidation", err)
+	}
+}
@@ -0,0 +1,79 @@
+package btcmarkets
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Environment groups the endpoints the client talks to, so the REST API and
+// the WebSocket feed can be redirected from one place.
+type Environment struct {
+	// BaseURL of the REST API, without the version path
+	BaseURL string
+
+	// WsURL of the WebSocket feed, without the version path. An http or
+	// https scheme is turned into ws or wss.
+	WsURL string
+
+	// APIVersion is the path of the REST API version e.g. "/v3"
+	APIVersion string
+
+	// WSVersion is the path of the WebSocket feed version e.g. "/v2"
+	WSVersion string
+}
+
+// ProductionEnvironment returns the BTCMarkets production endpoints. It is
+// used when ClientConfig.Environment is nil.
+func ProductionEnvironment() Environment {
+	return Environment{
+		BaseURL:    btcMarketsAPIURL,
+		WsURL:      btcMarketsWSURL,
+		APIVersion: btcMarketsAPIVersion,
+		WSVersion:  btcMarketsWSVersion,
+	}
+}
+
+// CustomEnvironment returns an environment serving the production API
+// versions from baseURL and wsURL, e.g. through a proxy. When wsURL is empty
+// the WebSocket feed is expected on baseURL.
+func CustomEnvironment(baseURL, wsURL string) Environment {
+	if wsURL == "" {
+		wsURL = baseURL
+	}
+
+	e := ProductionEnvironment()
+	e.BaseURL = baseURL
+	e.WsURL = wsURL
+	return e
+}
+
+// LocalEnvironment returns an environment pointing both the REST API and
+// the WebSocket feed at a local test server, e.g. the URL of an
+// httptest.Server.
+func LocalEnvironment(serverURL string) Environment {
+	return CustomEnvironment(serverURL, serverURL)
+}
+
+// endpoints resolves the REST and WebSocket URLs of the environment.
+func (e Environment) endpoints() (*url.URL, *url.URL, error) {
+	u, err := url.Parse(e.BaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = path.Join(u.Path, e.APIVersion)
+
+	wss, err := url.Parse(e.WsURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch strings.ToLower(wss.Scheme) {
+	case "http":
+		wss.Scheme = "ws"
+	case "https":
+		wss.Scheme = "wss"
+	}
+	wss.Path = path.Join(wss.Path, e.WSVersion)
+
+	return u, wss, nil
+}
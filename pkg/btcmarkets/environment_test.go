@@ -0,0 +1,75 @@
+package btcmarkets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestLocalEnvironment(t *testing.T) {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"timestamp": "2019-09-01T18:34:27.045000Z"}`))
+	})
+
+	subscribed := make(chan WSSubscribeMessage, 1)
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var m WSSubscribeMessage
+		if err := c.ReadJSON(&m); err == nil {
+			subscribed <- m
+		}
+		c.WriteMessage(websocket.TextMessage, []byte(`{"messageType":"heartbeat"}`))
+	})
+
+	env := LocalEnvironment(ts.URL)
+	client, err := NewBTCMClient(ClientConfig{Environment: &env, Httpclient: ts.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.WSURL.Scheme != "ws" || client.WSURL.Path != "/v2" {
+		t.Errorf("Unexpected WebSocket URL %v", client.WSURL)
+	}
+
+	if _, err := client.GetServerTime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := client.WebSocket.Subscribe(ctx, WSSubscribeMessage{Channels: []string{"tick"}, MarketIds: []string{"BTC-AUD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := <-subscribed
+	if m.MessageType != subscribe || m.Channels[0] != "tick" {
+		t.Errorf("Unexpected subscribe message %+v", m)
+	}
+	if msg := <-ch; string(msg) != `{"messageType":"heartbeat"}` {
+		t.Errorf("Unexpected message %s", msg)
+	}
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	env := CustomEnvironment("https://proxy.example.com/btcm", "")
+	client, err := NewBTCMClient(ClientConfig{Environment: &env, APIVersion: "/v4", WSVersion: "/v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.BaseURL.String(); got != "https://proxy.example.com/btcm/v4" {
+		t.Errorf("Unexpected base URL %s", got)
+	}
+	if got := client.WSURL.String(); got != "wss://proxy.example.com/btcm/v3" {
+		t.Errorf("Unexpected WebSocket URL %s", got)
+	}
+}
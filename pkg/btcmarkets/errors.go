@@ -0,0 +1,97 @@
+package btcmarkets
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Sentinel errors describing the category of a failed API call. An
+// *ErrorResponse returned by the client matches exactly one of these
+// through errors.Is, so retry and alerting logic can branch on the
+// category without inspecting the message text.
+var (
+	// ErrAuthentication is reported when the API key, signature or
+	// timestamp of an authenticated request was rejected.
+	ErrAuthentication = errors.New("btcmarkets: authentication failed")
+	// ErrValidation is reported when the exchange rejected the request
+	// parameters, e.g. an invalid price, amount or market id.
+	ErrValidation = errors.New("btcmarkets: request validation failed")
+	// ErrInsufficientFunds is reported when the account does not hold
+	// enough funds to place an order or request a withdrawal.
+	ErrInsufficientFunds = errors.New("btcmarkets: insufficient funds")
+	// ErrNotFound is reported when the requested resource does not exist.
+	ErrNotFound = errors.New("btcmarkets: resource not found")
+	// ErrRateLimited is reported when the request was throttled.
+	ErrRateLimited = errors.New("btcmarkets: rate limited")
+	// ErrServer is reported when the exchange failed to process the request.
+	ErrServer = errors.New("btcmarkets: server error")
+	// ErrMaintenance is reported when the exchange is unavailable because of
+	// scheduled maintenance.
+	ErrMaintenance = errors.New("btcmarkets: exchange under maintenance")
+	// ErrOrderFinal is reported when an order cannot be changed because it
+	// is already fully matched or cancelled.
+	ErrOrderFinal = errors.New("btcmarkets: order is already matched or cancelled")
+)
+
+// ErrNoCredentials is returned, before any network call, by authenticated
+// methods of a client created without API credentials.
+var ErrNoCredentials = errors.New("btcmarkets: API credentials are required for this request")
+
+// Error codes returned by BTCMarkets in the "code" attribute of an error body
+// that need to be mapped onto a category other than the one implied by the
+// HTTP status code.
+var errorCodeCategories = map[string]error{
+	"InvalidApiKey":         ErrAuthentication,
+	"InvalidAuthKey":        ErrAuthentication,
+	"InvalidAuthTimestamp":  ErrAuthentication,
+	"InvalidAuthSignature":  ErrAuthentication,
+	"Unauthorized":          ErrAuthentication,
+	"Forbidden":             ErrAuthentication,
+	"InsufficientFund":      ErrInsufficientFunds,
+	"InsufficientFunds":     ErrInsufficientFunds,
+	"NotFound":              ErrNotFound,
+	"OrderNotFound":         ErrNotFound,
+	"OrderAlreadyCancelled": ErrOrderFinal,
+	"OrderAlreadyMatched":   ErrOrderFinal,
+	"OrderStatusIsFinal":    ErrOrderFinal,
+	"TooManyRequests":       ErrRateLimited,
+	"Throttled":             ErrRateLimited,
+	"InternalServerError":   ErrServer,
+	"ServiceUnavailable":    ErrMaintenance,
+	"SystemMaintenance":     ErrMaintenance,
+	"MarketNotActive":       ErrMaintenance,
+}
+
+// orderFinalError marks an error returned by ReplaceOrder for an order that
+// can no longer be changed as ErrOrderFinal. The InvalidOrderStatus code only
+// has that meaning for a replacement, so it is not in errorCodeCategories.
+type orderFinalError struct {
+	err error
+}
+
+func (e orderFinalError) Error() string        { return e.err.Error() }
+func (e orderFinalError) Unwrap() error        { return e.err }
+func (e orderFinalError) Is(target error) bool { return target == ErrOrderFinal }
+
+// errorCategory returns the sentinel error matching the given HTTP status
+// code and BTCMarkets error code.
+func errorCategory(status int, code string) error {
+	if c, ok := errorCodeCategories[code]; ok {
+		return c
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuthentication
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusServiceUnavailable:
+		return ErrMaintenance
+	case status >= 500:
+		return ErrServer
+	default:
+		return ErrValidation
+	}
+}
@@ -0,0 +1,103 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCheckResponseErrorCategories(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+		want   error
+	}{
+		{
+			name:   "Insufficient funds",
+			status: http.StatusBadRequest,
+			body:   `{"code":"InsufficientFund","message":"insufficient fund"}`,
+			code:   "InsufficientFund",
+			want:   ErrInsufficientFunds,
+		},
+		{
+			name:   "Invalid price",
+			status: http.StatusBadRequest,
+			body:   `{"code":"InvalidPrice","message":"price is invalid"}`,
+			code:   "InvalidPrice",
+			want:   ErrValidation,
+		},
+		{
+			name:   "Invalid signature",
+			status: http.StatusUnauthorized,
+			body:   `{"code":"InvalidAuthSignature","message":"invalid signature"}`,
+			code:   "InvalidAuthSignature",
+			want:   ErrAuthentication,
+		},
+		{
+			name:   "Order already cancelled",
+			status: http.StatusBadRequest,
+			body:   `{"code":"OrderAlreadyCancelled","message":"order is already cancelled"}`,
+			code:   "OrderAlreadyCancelled",
+			want:   ErrOrderFinal,
+		},
+		{
+			name:   "Invalid order status",
+			status: http.StatusBadRequest,
+			body:   `{"code":"InvalidOrderStatus","message":"invalid order status"}`,
+			code:   "InvalidOrderStatus",
+			want:   ErrValidation,
+		},
+		{
+			name:   "Throttled",
+			status: http.StatusTooManyRequests,
+			body:   `{"code":"TooManyRequests","message":"too many requests"}`,
+			code:   "TooManyRequests",
+			want:   ErrRateLimited,
+		},
+		{
+			name:   "Non JSON server error",
+			status: http.StatusBadGateway,
+			body:   `<html>bad gateway</html>`,
+			want:   ErrServer,
+		},
+		{
+			name:   "Maintenance",
+			status: http.StatusServiceUnavailable,
+			body:   ``,
+			want:   ErrMaintenance,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mux, _, teardown, err := setup(nil)
+			defer teardown()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err = client.GetServerTime(context.Background())
+			if !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+
+			var er *ErrorResponse
+			if !errors.As(err, &er) {
+				t.Fatalf("Expected *ErrorResponse got %T", err)
+			}
+			if er.StatusCode != tt.status {
+				t.Errorf("Expected status %d got %d", tt.status, er.StatusCode)
+			}
+			if er.Code != tt.code {
+				t.Errorf("Expected code %q got %q", tt.code, er.Code)
+			}
+		})
+	}
+}
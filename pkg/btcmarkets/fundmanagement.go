@@ -1,6 +1,7 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
@@ -12,19 +13,19 @@ import (
 
 // WithdrawRequestCrypto is a generalized withdraw request type
 type WithdrawRequestCrypto struct {
-	Amount   float64 `json:"amount"`
+	Amount   Decimal `json:"amount"`
 	Currency string  `json:"currency"`
 	Address  string  `json:"address"`
 }
 
 // WithdrawRequestFiat is a generalized withdraw request type
 type WithdrawRequestFiat struct {
-	Amount        int64  `json:"amount"`
-	Currency      string `json:"currency"`
-	AccountName   string `json:"accountName"`
-	AccountNumber string `json:"accountNumber"`
-	BankName      string `json:"bankName"`
-	BSBNumber     string `json:"bsbNumber"`
+	Amount        Decimal `json:"amount"`
+	Currency      string  `json:"currency"`
+	AccountName   string  `json:"accountName"`
+	AccountNumber string  `json:"accountNumber"`
+	BankName      string  `json:"bankName"`
+	BSBNumber     string  `json:"bsbNumber"`
 }
 
 // PaymentDetails stores payment address
@@ -36,12 +37,12 @@ type PaymentDetails struct {
 type WithdrawData struct {
 	ID             string         `json:"id"`
 	AssetName      string         `json:"assetName"`
-	Amount         float64        `json:"amount,string"`
+	Amount         Decimal        `json:"amount"`
 	RequestType    string         `json:"type"`
 	CreationTime   time.Time      `json:"creationTime"`
 	Status         string         `json:"status"`
 	Description    string         `json:"description"`
-	Fee            float64        `json:"fee,string"`
+	Fee            Decimal        `json:"fee"`
 	LastUpdate     string         `json:"lastUpdate"`
 	PaymentDetails PaymentDetails `json:"paymentDetail,omitempty"`
 }
@@ -58,20 +59,20 @@ type DepositAddress struct {
 // WithdrawalFee stores data for fees
 type WithdrawalFee struct {
 	AssetName string  `json:"assetName"`
-	Fee       float64 `json:"fee,string"`
+	Fee       Decimal `json:"fee"`
 }
 
 // AssetData stores data for given asset
 type AssetData struct {
 	AssetName           string  `json:"assetName"`
-	MinDepositAmount    float64 `json:"minDepositAmount,string"`
-	MaxDepositAmount    float64 `json:"maxDepositAmount,string"`
+	MinDepositAmount    Decimal `json:"minDepositAmount"`
+	MaxDepositAmount    Decimal `json:"maxDepositAmount"`
 	DepositDecimals     float64 `json:"depositDecimals,string"`
-	MinWithdrawalAmount float64 `json:"minWithdrawalAmount,string"`
-	MaxWithdrawalAmount float64 `json:"maxWithdrawalAmount,string"`
+	MinWithdrawalAmount Decimal `json:"minWithdrawalAmount"`
+	MaxWithdrawalAmount Decimal `json:"maxWithdrawalAmount"`
 	WithdrawalDecimals  float64 `json:"withdrawalDecimals,string"`
-	WithdrawalFee       float64 `json:"withdrawalFee,string"`
-	DepositFee          float64 `json:"depositFee,string"`
+	WithdrawalFee       Decimal `json:"withdrawalFee"`
+	DepositFee          Decimal `json:"depositFee"`
 }
 
 // FundManagementServiceOp performs Fundmanagement operations on BTCMarkets
@@ -80,7 +81,7 @@ type FundManagementServiceOp struct {
 }
 
 // WithdrawCrypto This API is used to request to withdraw of crypto assets
-func (f *FundManagementServiceOp) WithdrawCrypto(assetName, toAddress string, amount float64) (WithdrawData, error) {
+func (f *FundManagementServiceOp) WithdrawCrypto(ctx context.Context, assetName, toAddress string, amount Decimal) (WithdrawData, error) {
 	var wd WithdrawData
 
 	wdreq := WithdrawRequestCrypto{
@@ -89,7 +90,7 @@ func (f *FundManagementServiceOp) WithdrawCrypto(assetName, toAddress string, am
 		Currency: assetName,
 	}
 
-	req, err := f.client.NewRequest(http.MethodPost, path.Join(btcMarketsWithdrawals), wdreq)
+	req, err := f.client.NewRequest(ctx, http.MethodPost, path.Join(btcMarketsWithdrawals), wdreq)
 	if err != nil {
 		return wd, err
 	}
@@ -102,7 +103,7 @@ func (f *FundManagementServiceOp) WithdrawCrypto(assetName, toAddress string, am
 }
 
 // WithdrawFiat This API is used to request to withdraw of crypto assets
-func (f *FundManagementServiceOp) WithdrawFiat(assetName, toAddress string, amount float64) (WithdrawData, error) {
+func (f *FundManagementServiceOp) WithdrawFiat(ctx context.Context, assetName, toAddress string, amount Decimal) (WithdrawData, error) {
 	var wdf WithdrawData
 
 	wdfreq := WithdrawRequestCrypto{
@@ -111,7 +112,7 @@ func (f *FundManagementServiceOp) WithdrawFiat(assetName, toAddress string, amou
 		Currency: assetName,
 	}
 
-	req, err := f.client.NewRequest(http.MethodPost, path.Join(btcMarketsWithdrawals), wdfreq)
+	req, err := f.client.NewRequest(ctx, http.MethodPost, path.Join(btcMarketsWithdrawals), wdfreq)
 	if err != nil {
 		return wdf, err
 	}
@@ -124,34 +125,23 @@ func (f *FundManagementServiceOp) WithdrawFiat(assetName, toAddress string, amou
 }
 
 // ListWithdrawls Returns list of withdrawals. This API supports pagination
-func (f *FundManagementServiceOp) ListWithdrawls(before, after int64, limit int32) ([]WithdrawData, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the
+// adjacent pages; see also IterateWithdrawals.
+func (f *FundManagementServiceOp) ListWithdrawls(ctx context.Context, before, after int64, limit int32) ([]WithdrawData, Cursor, error) {
 	var wd []WithdrawData
 
-	params := url.Values{}
-	if before > 0 {
-		params.Add("before", strconv.FormatInt(before, 10))
-	}
-	if limit > 0 {
-		params.Add("limit", strconv.Itoa(int(limit)))
-	}
-
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsWithdrawals, "?"+params.Encode()), nil)
+	c, err := f.list(ctx, btcMarketsWithdrawals, before, after, limit, &wd)
 	if err != nil {
-		return wd, err
-	}
-
-	_, err = f.client.DoAuthenticated(req, nil, &wd)
-	if err != nil {
-		return wd, err
+		return wd, c, err
 	}
-	return wd, nil
+	return wd, c, nil
 }
 
 // GetWithdrawal This API is used to request to get withdraw by id.
-func (f *FundManagementServiceOp) GetWithdrawal(orderID string) (WithdrawData, error) {
+func (f *FundManagementServiceOp) GetWithdrawal(ctx context.Context, orderID string) (WithdrawData, error) {
 	var wd WithdrawData
 
-	req, err := f.client.NewRequest(http.MethodDelete, path.Join(btcMarketsWithdrawals, orderID), nil)
+	req, err := f.client.NewRequest(ctx, http.MethodDelete, path.Join(btcMarketsWithdrawals, orderID), nil)
 	if err != nil {
 		return wd, err
 	}
@@ -165,35 +155,24 @@ func (f *FundManagementServiceOp) GetWithdrawal(orderID string) (WithdrawData, e
 }
 
 // ListDeposits Returns list of depoists. This API supports pagination
-func (f *FundManagementServiceOp) ListDeposits(before, after int64, limit int32) ([]WithdrawData, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the
+// adjacent pages; see also IterateDeposits.
+func (f *FundManagementServiceOp) ListDeposits(ctx context.Context, before, after int64, limit int32) ([]WithdrawData, Cursor, error) {
 	var wd []WithdrawData
 
-	params := url.Values{}
-	if before > 0 {
-		params.Add("before", strconv.FormatInt(before, 10))
-	}
-	if limit > 0 {
-		params.Add("limit", strconv.Itoa(int(limit)))
-	}
-
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsDeposits, "?"+params.Encode()), nil)
+	c, err := f.list(ctx, btcMarketsDeposits, before, after, limit, &wd)
 	if err != nil {
-		return wd, err
+		return wd, c, err
 	}
-
-	_, err = f.client.DoAuthenticated(req, nil, &wd)
-	if err != nil {
-		return wd, err
-	}
-	return wd, nil
+	return wd, c, nil
 }
 
 // GetDeposit This API returns a deposit by id.
-func (f *FundManagementServiceOp) GetDeposit(orderID string) (WithdrawData, error) {
+func (f *FundManagementServiceOp) GetDeposit(ctx context.Context, orderID string) (WithdrawData, error) {
 	// TODO: WithdrawData may be incompatible for this reponse data received from this endpoint
 	var wd WithdrawData
 
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsDeposits, orderID), nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsDeposits, orderID), nil)
 	if err != nil {
 		return wd, err
 	}
@@ -207,42 +186,107 @@ func (f *FundManagementServiceOp) GetDeposit(orderID string) (WithdrawData, erro
 }
 
 // ListTransfers A transfer record refers either to a deposit or withdraw and this API returns list of transfers covering both depoists and withdrawals. This API supports pagination
-func (f *FundManagementServiceOp) ListTransfers(before, after int64, limit int32) ([]TransferData, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the adjacent pages; see also IterateTransfers.
+func (f *FundManagementServiceOp) ListTransfers(ctx context.Context, before, after int64, limit int32) ([]TransferData, Cursor, error) {
 	var t []TransferData
 
-	if (before > 0) && (after >= 0) {
-		return nil, errors.New("BTCMarkets only supports either before or after, not both")
+	c, err := f.list(ctx, btcMarketsTransfers, before, after, limit, &t)
+	if err != nil {
+		return t, c, err
+	}
+
+	return t, c, nil
+}
+
+// list fetches a page of the paginated transfer endpoint at p into v.
+func (f *FundManagementServiceOp) list(ctx context.Context, p string, before, after int64, limit int32, v interface{}) (Cursor, error) {
+	if (before > 0) && (after > 0) {
+		return Cursor{}, errors.New("BTCMarkets only supports either before or after, not both")
 	}
 
 	params := url.Values{}
 	if before > 0 {
 		params.Set("before", strconv.FormatInt(before, 10))
 	}
-	if after >= 0 {
+	if after > 0 {
 		params.Set("after", strconv.FormatInt(after, 10))
 	}
 	if limit > 0 {
 		params.Add("limit", strconv.Itoa(int(limit)))
 	}
 
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsTransfers, "?"+params.Encode()), nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, path.Join(p, "?"+params.Encode()), nil)
 	if err != nil {
-		return t, err
+		return Cursor{}, err
 	}
 
-	_, err = f.client.DoAuthenticated(req, nil, &t)
+	resp, err := f.client.DoAuthenticated(req, nil, v)
 	if err != nil {
-		return t, err
+		return Cursor{}, err
 	}
+	return cursorFromResponse(resp), nil
+}
+
+// TransferIterator walks withdrawals, deposits or both, newest first,
+// fetching one page at a time.
+type TransferIterator struct {
+	pager
+	svc  *FundManagementServiceOp
+	path string
+	buf  []WithdrawData
+	cur  WithdrawData
+
+	// Stop, if set, ends the walk at the first transfer it returns true for.
+	Stop func(WithdrawData) bool
+}
+
+// IterateWithdrawals returns an iterator over the withdrawals of the account.
+func (f *FundManagementServiceOp) IterateWithdrawals(ctx context.Context, opts IteratorOptions) *TransferIterator {
+	return &TransferIterator{pager: newPager(ctx, opts), svc: f, path: btcMarketsWithdrawals}
+}
+
+// IterateDeposits returns an iterator over the deposits of the account.
+func (f *FundManagementServiceOp) IterateDeposits(ctx context.Context, opts IteratorOptions) *TransferIterator {
+	return &TransferIterator{pager: newPager(ctx, opts), svc: f, path: btcMarketsDeposits}
+}
+
+// IterateTransfers returns an iterator over both the deposits and the
+// withdrawals of the account.
+func (f *FundManagementServiceOp) IterateTransfers(ctx context.Context, opts IteratorOptions) *TransferIterator {
+	return &TransferIterator{pager: newPager(ctx, opts), svc: f, path: btcMarketsTransfers}
+}
+
+// Next advances to the next transfer and reports whether there is one.
+func (it *TransferIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for len(it.buf) == 0 {
+		if !it.fetchPage(it.fetch) {
+			return false
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return !it.stopAt(it.cur.CreationTime, it.Stop != nil && it.Stop(it.cur))
+}
+
+// Transfer returns the current transfer.
+func (it *TransferIterator) Transfer() WithdrawData {
+	return it.cur
+}
 
-	return t, nil
+func (it *TransferIterator) fetch(after int64, limit int) (int, Cursor, error) {
+	it.buf = nil
+	c, err := it.svc.list(it.ctx, it.path, 0, after, int32(limit), &it.buf)
+	return len(it.buf), c, err
 }
 
 // GetTransfers This API retruns either deposit or withdrawal by id
-func (f *FundManagementServiceOp) GetTransfers(orderID string) (WithdrawData, error) {
+func (f *FundManagementServiceOp) GetTransfers(ctx context.Context, orderID string) (WithdrawData, error) {
 	var wd WithdrawData
 
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsTransfers, orderID), nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsTransfers, orderID), nil)
 	if err != nil {
 		return wd, err
 	}
@@ -257,7 +301,7 @@ func (f *FundManagementServiceOp) GetTransfers(orderID string) (WithdrawData, er
 
 // GetDepositeAddress returns deposit address for the given asset
 // Note: The documentation at https://api.btcmarkets.net/doc/v3#tag/Fund-Management-APIs/paths/~1v3~1addresses/get is wrong
-func (f *FundManagementServiceOp) GetDepositeAddress(assetName string, before, after, limit int64) (DepositAddress, error) {
+func (f *FundManagementServiceOp) GetDepositeAddress(ctx context.Context, assetName string, before, after, limit int64) (DepositAddress, error) {
 	var da DepositAddress
 
 	if (before > 0) && (after > 0) {
@@ -276,7 +320,7 @@ func (f *FundManagementServiceOp) GetDepositeAddress(assetName string, before, a
 		params.Set("limit", strconv.FormatInt(limit, 10))
 	}
 
-	req, err := f.client.NewRequest(http.MethodGet, path.Join(btcMarketsAddresses, "?"+params.Encode()), nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAddresses, "?"+params.Encode()), nil)
 	if err != nil {
 		return da, err
 	}
@@ -291,10 +335,10 @@ func (f *FundManagementServiceOp) GetDepositeAddress(assetName string, before, a
 
 // GetWithdrawalFees Returns fees associated with withdrawals.
 // This API is public and does not require authentication as the fees as system wide and published on the website
-func (f *FundManagementServiceOp) GetWithdrawalFees() ([]WithdrawalFee, error) {
+func (f *FundManagementServiceOp) GetWithdrawalFees(ctx context.Context) ([]WithdrawalFee, error) {
 	var wf []WithdrawalFee
 
-	req, err := f.client.NewRequest(http.MethodGet, btcMarketsWithdrawalFees, nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, btcMarketsWithdrawalFees, nil)
 	if err != nil {
 		return wf, err
 	}
@@ -318,10 +362,10 @@ func (f *FundManagementServiceOp) GetWithdrawalFees() ([]WithdrawalFee, error) {
 // maxWithdrawalAmount: maximum amount to withdraw
 // withdrawalFee: withdrawal fee
 // withdrawalDecimals: number of decimal places allowed for withdrawals
-func (f *FundManagementServiceOp) ListAssets() ([]AssetData, error) {
+func (f *FundManagementServiceOp) ListAssets(ctx context.Context) ([]AssetData, error) {
 	var la []AssetData
 
-	req, err := f.client.NewRequest(http.MethodGet, btcMarketsAssets, nil)
+	req, err := f.client.NewRequest(ctx, http.MethodGet, btcMarketsAssets, nil)
 	if err != nil {
 		return la, err
 	}
@@ -0,0 +1,204 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Logger is a leveled logger taking alternating key/value pairs after the
+// message, in the style of log/slog. A *slog.Logger satisfies it directly;
+// other backends can be adapted with LogFunc.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// LogLevel is the severity of a log entry passed to a LogFunc.
+type LogLevel int
+
+// Log levels, matching the values used by log/slog
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// LogFunc adapts a key/value logging function to the Logger interface.
+type LogFunc func(level LogLevel, msg string, keysAndValues ...interface{})
+
+// Debug logs at LevelDebug.
+func (f LogFunc) Debug(msg string, keysAndValues ...interface{}) {
+	f(LevelDebug, msg, keysAndValues...)
+}
+
+// Info logs at LevelInfo.
+func (f LogFunc) Info(msg string, keysAndValues ...interface{}) {
+	f(LevelInfo, msg, keysAndValues...)
+}
+
+// Warn logs at LevelWarn.
+func (f LogFunc) Warn(msg string, keysAndValues ...interface{}) {
+	f(LevelWarn, msg, keysAndValues...)
+}
+
+// Error logs at LevelError.
+func (f LogFunc) Error(msg string, keysAndValues ...interface{}) {
+	f(LevelError, msg, keysAndValues...)
+}
+
+// NewStdLogger returns a Logger writing entries at or above min to l in the
+// key=value text format of log/slog.
+func NewStdLogger(l *log.Logger, min LogLevel) Logger {
+	return LogFunc(func(level LogLevel, msg string, keysAndValues ...interface{}) {
+		if level < min {
+			return
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "level=%s msg=%q", level, msg)
+		for i := 0; i < len(keysAndValues); i += 2 {
+			k := fmt.Sprint(keysAndValues[i])
+			if i+1 >= len(keysAndValues) {
+				fmt.Fprintf(&b, " !BADKEY=%q", k)
+				break
+			}
+			fmt.Fprintf(&b, " %s=%q", k, fmt.Sprint(keysAndValues[i+1]))
+		}
+		l.Print(b.String())
+	})
+}
+
+// NopLogger discards every entry. It is used when ClientConfig.Logger is nil.
+type NopLogger struct{}
+
+// Debug discards the entry.
+func (NopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// Info discards the entry.
+func (NopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// Warn discards the entry.
+func (NopLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+// Error discards the entry.
+func (NopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are removed from logged headers and from the request kept
+// by ErrorResponse.
+var sensitiveHeaders = []string{"BM-AUTH-SIGNATURE", "BM-AUTH-APIKEY"}
+
+// sensitiveKeys are log keys whose values are never logged.
+var sensitiveKeys = []string{"signature", "apikey", "api_key", "apisecret", "api_secret", "secret", "bm-auth-signature", "bm-auth-apikey"}
+
+// redactHeader returns a copy of h without the values of sensitive headers.
+func redactHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = v
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := c[http.CanonicalHeaderKey(k)]; ok {
+			c.Set(k, redacted)
+		}
+	}
+	return c
+}
+
+// redactingLogger scrubs credentials from every entry before handing it to
+// the wrapped Logger.
+type redactingLogger struct {
+	next    Logger
+	secrets []string
+}
+
+// newRedactingLogger wraps l so that the given secrets, the API key and
+// secret, never reach it.
+func newRedactingLogger(l Logger, secrets ...string) Logger {
+	if l == nil {
+		return NopLogger{}
+	}
+
+	r := &redactingLogger{next: l}
+	for _, s := range secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	return r
+}
+
+func (r *redactingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	r.next.Debug(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	r.next.Info(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	r.next.Warn(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Error(msg string, keysAndValues ...interface{}) {
+	r.next.Error(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+// scrub replaces the secrets found in s.
+func (r *redactingLogger) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+// redact returns a copy of keysAndValues where the values of sensitive keys
+// are dropped, headers are redacted and secrets are scrubbed from strings
+// and errors.
+func (r *redactingLogger) redact(keysAndValues []interface{}) []interface{} {
+	out := make([]interface{}, len(keysAndValues))
+	for i, v := range keysAndValues {
+		if i%2 == 1 {
+			if k, ok := keysAndValues[i-1].(string); ok && stringInArray(strings.ToLower(k), sensitiveKeys) {
+				out[i] = redacted
+				continue
+			}
+		}
+
+		switch t := v.(type) {
+		case string:
+			out[i] = r.scrub(t)
+		case error:
+			out[i] = r.scrub(t.Error())
+		case http.Header:
+			out[i] = redactHeader(t)
+		case fmt.Stringer:
+			out[i] = r.scrub(t.String())
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
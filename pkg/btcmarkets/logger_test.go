@@ -0,0 +1,54 @@
+package btcmarkets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactingLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newRedactingLogger(NewStdLogger(log.New(&buf, "", 0), LevelDebug), "my-api-key", "my-secret")
+
+	h := http.Header{}
+	h.Set("BM-AUTH-SIGNATURE", "c2lnbmF0dXJl")
+	h.Set("BM-AUTH-APIKEY", "my-api-key")
+	l.Info("request with my-api-key", "headers", h, "signature", "c2lnbmF0dXJl", "error", errors.New("bad my-secret"))
+
+	out := buf.String()
+	for _, s := range []string{"my-api-key", "my-secret", "c2lnbmF0dXJl"} {
+		if strings.Contains(out, s) {
+			t.Errorf("Log output leaked %q: %s", s, out)
+		}
+	}
+	if !strings.Contains(out, "level=INFO") {
+		t.Errorf("Expected slog style output got %s", out)
+	}
+}
+
+func TestErrorResponseRedactsRequest(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/v3/accounts/me/balances", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"InvalidAuthSignature","message":"invalid signature"}`))
+	})
+
+	_, err = client.Account.GetBalances(context.Background())
+	var er *ErrorResponse
+	if !errors.As(err, &er) {
+		t.Fatalf("Expected *ErrorResponse got %v", err)
+	}
+	for _, k := range sensitiveHeaders {
+		if v := er.Response.Request.Header.Get(k); v != redacted {
+			t.Errorf("Expected %s to be redacted got %q", k, v)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
@@ -42,8 +43,8 @@ type Market struct {
 	MarketID       string  `json:"marketId"`
 	BaseAsset      string  `json:"baseAssetName"`
 	QuoteAsset     string  `json:"quoteAssetName"`
-	MinOrderAmount float64 `json:"minOrderAmount,string"`
-	MaxOrderAmount float64 `json:"maxOrderAmount,string"`
+	MinOrderAmount Decimal `json:"minOrderAmount"`
+	MaxOrderAmount Decimal `json:"maxOrderAmount"`
 	AmountDecimals int64   `json:"amountDecimals,string"`
 	PriceDecimals  int64   `json:"priceDecimals,string"`
 }
@@ -51,48 +52,48 @@ type Market struct {
 // Ticker holds ticker information
 type Ticker struct {
 	MarketID  string    `json:"marketId"`
-	BestBID   float64   `json:"bestBid,string"`
-	BestAsk   float64   `json:"bestAsk,string"`
-	LastPrice float64   `json:"lastPrice,string"`
-	Volume    float64   `json:"volume24h,string"`
-	Change24h float64   `json:"price24h,string"`
-	Low24h    float64   `json:"low24h,string"`
-	High24h   float64   `json:"high24h,string"`
+	BestBID   Decimal   `json:"bestBid"`
+	BestAsk   Decimal   `json:"bestAsk"`
+	LastPrice Decimal   `json:"lastPrice"`
+	Volume    Decimal   `json:"volume24h"`
+	Change24h Decimal   `json:"price24h"`
+	Low24h    Decimal   `json:"low24h"`
+	High24h   Decimal   `json:"high24h"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // Trade holds trade information
 type Trade struct {
 	TradeID   string    `json:"id"`
-	Amount    float64   `json:"amount,string"`
-	Price     float64   `json:"price,string"`
+	Amount    Decimal   `json:"amount"`
+	Price     Decimal   `json:"price"`
 	Timestamp time.Time `json:"timestamp"`
-	Side      string    `json:"side"`
+	Side      Side      `json:"side"`
 }
 
 // OrderBook holds current orderbook information returned from the exchange
 type OrderBook struct {
-	MarketID   string     `json:"marketId"`
-	SnapshotID int        `json:"snapshotId"`
-	Asks       [][]string `json:"asks"`
-	Bids       [][]string `json:"bids"`
+	MarketID   string      `json:"marketId"`
+	SnapshotID int         `json:"snapshotId"`
+	Asks       [][]Decimal `json:"asks"`
+	Bids       [][]Decimal `json:"bids"`
 }
 
 // Candle holds time, open, high, low, close & volume information for a given trading pair
 type Candle struct {
 	Time   time.Time
-	Open   float64
-	Close  float64
-	Low    float64
-	High   float64
-	Volume float64
+	Open   Decimal
+	Close  Decimal
+	Low    Decimal
+	High   Decimal
+	Volume Decimal
 }
 
 //AllMarkets Retrieves list of active markets including configuration for each market
-func (s *MarketServiceOp) AllMarkets() ([]Market, error) {
+func (s *MarketServiceOp) AllMarkets(ctx context.Context) ([]Market, error) {
 	var markets []Market
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -107,10 +108,10 @@ func (s *MarketServiceOp) AllMarkets() ([]Market, error) {
 }
 
 //GetMarketTicker Returns ticker for the given marketId
-func (s *MarketServiceOp) GetMarketTicker(marketID string) (*Ticker, error) {
+func (s *MarketServiceOp) GetMarketTicker(ctx context.Context, marketID string) (*Ticker, error) {
 	var ticker Ticker
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsGetTicker), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsGetTicker), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -124,43 +125,90 @@ func (s *MarketServiceOp) GetMarketTicker(marketID string) (*Ticker, error) {
 }
 
 //GetMarketTrades Retrieves list of most recent trades for the given market. This API supports pagination.
-func (s *MarketServiceOp) GetMarketTrades(marketID string, after, before, limit int) ([]Trade, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the adjacent pages; see also
+// MarketTradeIterator.
+func (s *MarketServiceOp) GetMarketTrades(ctx context.Context, marketID string, after, before, limit int) ([]Trade, Cursor, error) {
 	var trades []Trade
 	params := url.Values{}
 
 	if after > 0 && before > 0 {
-		return nil, errors.New("Using `before` and `after` simultaneously is not supported")
+		return nil, Cursor{}, errors.New("Using `before` and `after` simultaneously is not supported")
 	}
 
 	if limit > 0 {
 		params.Set("limit", strconv.Itoa(limit))
 	}
-	if after >= 0 {
+	if after > 0 {
 		params.Set("after", strconv.Itoa(after))
 	}
 	if before > 0 {
 		params.Set("before", strconv.Itoa(before))
 	}
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsGetTrades+params.Encode()), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsGetTrades+params.Encode()), nil)
 
 	if err != nil {
-		return nil, err
+		return nil, Cursor{}, err
 	}
 
-	_, err = s.client.Do(req, &trades)
+	resp, err := s.client.Do(req, &trades)
 	if err != nil {
-		return nil, err
+		return nil, Cursor{}, err
 	}
 
-	return trades, nil
+	return trades, cursorFromResponse(resp), nil
+}
+
+// MarketTradeIterator walks the public trades of a market, newest first,
+// fetching one page at a time.
+type MarketTradeIterator struct {
+	pager
+	svc      *MarketServiceOp
+	marketID string
+	buf      []Trade
+	cur      Trade
+
+	// Stop, if set, ends the walk at the first trade it returns true for.
+	Stop func(Trade) bool
+}
+
+// IterateMarketTrades returns an iterator over the public trades of marketID.
+func (s *MarketServiceOp) IterateMarketTrades(ctx context.Context, marketID string, opts IteratorOptions) *MarketTradeIterator {
+	return &MarketTradeIterator{pager: newPager(ctx, opts), svc: s, marketID: marketID}
+}
+
+// Next advances to the next trade and reports whether there is one.
+func (it *MarketTradeIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for len(it.buf) == 0 {
+		if !it.fetchPage(it.fetch) {
+			return false
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return !it.stopAt(it.cur.Timestamp, it.Stop != nil && it.Stop(it.cur))
+}
+
+// Trade returns the current trade.
+func (it *MarketTradeIterator) Trade() Trade {
+	return it.cur
+}
+
+func (it *MarketTradeIterator) fetch(after int64, limit int) (int, Cursor, error) {
+	var c Cursor
+	var err error
+	it.buf, c, err = it.svc.GetMarketTrades(it.ctx, it.marketID, int(after), 0, limit)
+	return len(it.buf), c, err
 }
 
 // GetMarketOrderbook Retrieves list of bids and asks for a given market. passing level=1 returns top 50 for bids and asks.
 // level=2 returns full orderbook (full orderbook data is cached and usually updated every 10 seconds).
 // Each market order is represented as an array of string [price, volume]. The attribute, snapshotId, is a
 // uniqueue number associated to orderbook and it changes every time orderbook changes.
-func (s *MarketServiceOp) GetMarketOrderbook(marketID string, level int) (*OrderBook, error) {
+func (s *MarketServiceOp) GetMarketOrderbook(ctx context.Context, marketID string, level int) (*OrderBook, error) {
 	var orderbooks OrderBook
 	params := url.Values{}
 
@@ -169,7 +217,7 @@ func (s *MarketServiceOp) GetMarketOrderbook(marketID string, level int) (*Order
 	}
 	params.Set("level", strconv.Itoa(level))
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketOrderBooks+params.Encode()), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketOrderBooks+params.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +238,7 @@ func (s *MarketServiceOp) GetMarketOrderbook(marketID string, level int) (*Order
 // When using timestamp parameters as query string, the maximum number of items that can be retrieved is 1000, and depending
 // on the specified timeWindow this can be different time windows. For instance, when using timeWindow=1d then up to 1000 days
 // of market candles can be retrieved.
-func (s *MarketServiceOp) GetMarketCandles(marketID, timeWindow string, from, to *time.Time, before, after, limit int) ([]Candle, error) {
+func (s *MarketServiceOp) GetMarketCandles(ctx context.Context, marketID, timeWindow string, from, to *time.Time, before, after, limit int) ([]Candle, error) {
 	var candles []Candle
 	var temp [][]string // required to parse time.Time and int into Candles struct
 
@@ -222,7 +270,7 @@ func (s *MarketServiceOp) GetMarketCandles(marketID, timeWindow string, from, to
 		params.Set("before", strconv.Itoa(before))
 	}
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsCandles+params.Encode()), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, marketID, btcMarketsCandles+params.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -240,23 +288,23 @@ func (s *MarketServiceOp) GetMarketCandles(marketID, timeWindow string, from, to
 		}
 		candle.Time = t
 
-		candle.Open, err = strconv.ParseFloat(temp[i][1], 64)
+		candle.Open, err = ParseDecimal(temp[i][1])
 		if err != nil {
 			return nil, err
 		}
-		candle.High, err = strconv.ParseFloat(temp[i][2], 64)
+		candle.High, err = ParseDecimal(temp[i][2])
 		if err != nil {
 			return nil, err
 		}
-		candle.Low, err = strconv.ParseFloat(temp[i][3], 64)
+		candle.Low, err = ParseDecimal(temp[i][3])
 		if err != nil {
 			return nil, err
 		}
-		candle.Close, err = strconv.ParseFloat(temp[i][4], 64)
+		candle.Close, err = ParseDecimal(temp[i][4])
 		if err != nil {
 			return nil, err
 		}
-		candle.Volume, err = strconv.ParseFloat(temp[i][5], 64)
+		candle.Volume, err = ParseDecimal(temp[i][5])
 		if err != nil {
 			return nil, err
 		}
@@ -268,7 +316,7 @@ func (s *MarketServiceOp) GetMarketCandles(marketID, timeWindow string, from, to
 // GetMultipleTickers This API works similar to /v3/markets/{marketId}/ticker except it retrieves tickers for a given list of marketIds
 // provided via query string (e.g. ?marketId=ETH-BTC&marketId=XRP-BTC).
 // To gain better performance, restrict the number of marketIds to the items needed for your trading app instead of requesting all markets.
-func (s *MarketServiceOp) GetMultipleTickers(marketIDs []string) ([]Ticker, error) {
+func (s *MarketServiceOp) GetMultipleTickers(ctx context.Context, marketIDs []string) ([]Ticker, error) {
 	var tickers []Ticker
 	param := url.Values{}
 
@@ -276,7 +324,7 @@ func (s *MarketServiceOp) GetMultipleTickers(marketIDs []string) ([]Ticker, erro
 		param.Add("marketId", v)
 	}
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, btcMarketsGetTickers+param.Encode()), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, btcMarketsGetTickers+param.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +341,7 @@ func (s *MarketServiceOp) GetMultipleTickers(marketIDs []string) ([]Ticker, erro
 // To gain better performance, restrict the number of marketIds to the items needed for your trading app instead of requesting all markets.
 // Retrieving full orderbook (level=2), for multiple markets, was mainly provided for customers who are interested in capturing and keeping full orderbook history.
 // Therefore, it's recommended to call this API with lower frequency as the data size can be large and also cached.
-func (s *MarketServiceOp) GetMultipleOrderbooks(marketIDs []string, level int) ([]OrderBook, error) {
+func (s *MarketServiceOp) GetMultipleOrderbooks(ctx context.Context, marketIDs []string, level int) ([]OrderBook, error) {
 	var orderbooks []OrderBook
 	params := url.Values{}
 
@@ -306,7 +354,7 @@ func (s *MarketServiceOp) GetMultipleOrderbooks(marketIDs []string, level int) (
 		params.Add("marketId", marketIDs[i])
 	}
 
-	req, err := s.client.NewRequest(http.MethodGet, path.Join(btcMarketsAllMarkets, btcMarketMultipleOrderBooks+params.Encode()), nil)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsAllMarkets, btcMarketMultipleOrderBooks+params.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,149 @@
+package btcmarkets
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MarketRules is a registry of the trading rules of every market, i.e. the
+// minimum and maximum order amounts and the number of decimals accepted for
+// amounts and prices. It is loaded from AllMarkets the first time it is
+// used and reloaded with Refresh, e.g. after a market listing change.
+//
+// When ClientConfig.EnforceMarketRules is set, PlaceNewOrder, ReplaceOrder
+// and BatchPlaceCancelOrders check orders against the registry so invalid
+// orders fail locally instead of being rejected by the exchange.
+type MarketRules struct {
+	market *MarketServiceOp
+
+	mu       sync.RWMutex
+	markets  map[string]Market
+	loadedAt time.Time
+}
+
+func newMarketRules(m *MarketServiceOp) *MarketRules {
+	return &MarketRules{market: m}
+}
+
+// Refresh reloads the rules of every market from the exchange.
+func (r *MarketRules) Refresh(ctx context.Context) error {
+	markets, err := r.market.AllMarkets(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]Market, len(markets))
+	for _, mk := range markets {
+		m[mk.MarketID] = mk
+	}
+
+	r.mu.Lock()
+	r.markets = m
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadedAt returns when the rules were last loaded, or the zero time if
+// they never were.
+func (r *MarketRules) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
+
+// Market returns the rules of marketID, loading the registry if needed.
+// Errors match ErrValidation when the market is unknown.
+func (r *MarketRules) Market(ctx context.Context, marketID string) (Market, error) {
+	r.mu.RLock()
+	loaded := r.markets != nil
+	r.mu.RUnlock()
+
+	if !loaded {
+		if err := r.Refresh(ctx); err != nil {
+			return Market{}, err
+		}
+	}
+
+	r.mu.RLock()
+	m, ok := r.markets[marketID]
+	r.mu.RUnlock()
+	if !ok {
+		return Market{}, fmt.Errorf("btcmarkets: unknown market %q: %w", marketID, ErrValidation)
+	}
+	return m, nil
+}
+
+// ValidateOrder checks the amount and prices of order against the rules of
+// its market. Errors match ErrValidation.
+func (r *MarketRules) ValidateOrder(ctx context.Context, order *NewOrderRequest) error {
+	return r.validate(ctx, order.MarketID, order.Amount, order.Price, order.TriggerPrice)
+}
+
+// RoundOrder rounds the amount and prices of order to the decimals accepted
+// by its market, see RoundAmount and RoundPrice.
+func (r *MarketRules) RoundOrder(ctx context.Context, order *NewOrderRequest) error {
+	m, err := r.Market(ctx, order.MarketID)
+	if err != nil {
+		return err
+	}
+
+	if order.Amount != nil {
+		a := m.RoundAmount(*order.Amount)
+		order.Amount = &a
+	}
+	if order.Price != nil {
+		p := m.RoundPrice(order.Side, *order.Price)
+		order.Price = &p
+	}
+	if order.TriggerPrice != nil {
+		p := m.RoundPrice(order.Side, *order.TriggerPrice)
+		order.TriggerPrice = &p
+	}
+	return nil
+}
+
+// validate checks amount and prices against the rules of marketID. A nil
+// amount, i.e. a market order for a target amount, and nil prices are
+// skipped.
+func (r *MarketRules) validate(ctx context.Context, marketID string, amount *Decimal, prices ...*Decimal) error {
+	m, err := r.Market(ctx, marketID)
+	if err != nil {
+		return err
+	}
+
+	if amount != nil {
+		if amount.LessThan(m.MinOrderAmount) {
+			return fmt.Errorf("btcmarkets: %s amount %s is below the minimum of %s: %w", marketID, amount, m.MinOrderAmount, ErrValidation)
+		}
+		if !m.MaxOrderAmount.IsZero() && amount.GreaterThan(m.MaxOrderAmount) {
+			return fmt.Errorf("btcmarkets: %s amount %s is above the maximum of %s: %w", marketID, amount, m.MaxOrderAmount, ErrValidation)
+		}
+		if !m.RoundAmount(*amount).Equal(*amount) {
+			return fmt.Errorf("btcmarkets: %s amount %s has more than %d decimals: %w", marketID, amount, m.AmountDecimals, ErrValidation)
+		}
+	}
+	for _, p := range prices {
+		if p != nil && !p.Truncate(int32(m.PriceDecimals)).Equal(*p) {
+			return fmt.Errorf("btcmarkets: %s price %s has more than %d decimals: %w", marketID, p, m.PriceDecimals, ErrValidation)
+		}
+	}
+	return nil
+}
+
+// RoundAmount floors amount to the amount decimals of the market, so an
+// order never exceeds the intended size.
+func (m Market) RoundAmount(amount Decimal) Decimal {
+	return amount.Floor(int32(m.AmountDecimals))
+}
+
+// RoundPrice rounds price to the price decimals of the market in the
+// direction that favours side: down for a bid and up for an ask.
+func (m Market) RoundPrice(side Side, price Decimal) Decimal {
+	if side == SideAsk {
+		return price.Ceil(int32(m.PriceDecimals))
+	}
+	return price.Floor(int32(m.PriceDecimals))
+}
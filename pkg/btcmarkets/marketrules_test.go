@@ -0,0 +1,116 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const marketsBody = `[
+	{
+		"marketId": "BTC-AUD",
+		"baseAssetName": "BTC",
+		"quoteAssetName": "AUD",
+		"minOrderAmount": "0.0001",
+		"maxOrderAmount": "1000000",
+		"amountDecimals": "8",
+		"priceDecimals": "2"
+	}
+]`
+
+func TestMarketRoundPrice(t *testing.T) {
+	m := Market{AmountDecimals: 8, PriceDecimals: 2}
+
+	if got := m.RoundPrice(SideBid, MustParseDecimal("100.129")); got.String() != "100.12" {
+		t.Errorf("RoundPrice(Bid) = %s; want 100.12", got)
+	}
+	if got := m.RoundPrice(SideAsk, MustParseDecimal("100.121")); got.String() != "100.13" {
+		t.Errorf("RoundPrice(Ask) = %s; want 100.13", got)
+	}
+	if got := m.RoundAmount(MustParseDecimal("0.123456789")); got.String() != "0.12345678" {
+		t.Errorf("RoundAmount = %s; want 0.12345678", got)
+	}
+}
+
+func TestEnforceMarketRules(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.enforceMarketRules = true
+
+	loads := 0
+	mux.HandleFunc("/v3/markets", func(w http.ResponseWriter, r *http.Request) {
+		loads++
+		w.Write([]byte(marketsBody))
+	})
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orderId":"1","marketId":"BTC-AUD","side":"Bid","type":"Limit","status":"Accepted"}`))
+	})
+
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		order *NewOrderRequest
+		valid bool
+	}{
+		{"Valid", NewLimitOrder("BTC-AUD", SideBid, MustParseDecimal("100.12"), MustParseDecimal("0.01")), true},
+		{"Below minimum", NewLimitOrder("BTC-AUD", SideBid, MustParseDecimal("100"), MustParseDecimal("0.00001")), false},
+		{"Above maximum", NewMarketOrder("BTC-AUD", SideBid, MustParseDecimal("1000001")), false},
+		{"Too many price decimals", NewLimitOrder("BTC-AUD", SideBid, MustParseDecimal("100.123"), MustParseDecimal("0.01")), false},
+		{"Too many trigger decimals", NewStopOrder("BTC-AUD", SideAsk, MustParseDecimal("99.001"), MustParseDecimal("0.01")), false},
+		{"Unknown market", NewMarketOrder("XYZ-AUD", SideBid, MustParseDecimal("1")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Order.PlaceNewOrder(ctx, tt.order)
+			if tt.valid && err != nil {
+				t.Errorf("PlaceNewOrder error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrValidation) {
+				t.Errorf("PlaceNewOrder error = %v; want ErrValidation", err)
+			}
+		})
+	}
+	if loads != 1 {
+		t.Errorf("markets loaded %d times; want 1", loads)
+	}
+
+	price := MustParseDecimal("100.001")
+	_, err = client.Batch.BatchPlaceCancelOrders(ctx, nil, []PlaceBatch{{
+		MarketID: "BTC-AUD", Price: &price, Amount: MustParseDecimal("1"),
+		OrderType: OrderTypeLimit, Side: SideBid, ClientOrderID: "a",
+	}})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("BatchPlaceCancelOrders error = %v; want ErrValidation", err)
+	}
+
+	replaced := 0
+	mux.HandleFunc("/v3/orders/7524", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			replaced++
+		}
+		w.Write([]byte(`{"orderId":"7524","marketId":"BTC-AUD","side":"Bid","type":"Limit","status":"Accepted"}`))
+	})
+	_, err = client.Order.ReplaceOrder(ctx, "7524", MustParseDecimal("100.001"), MustParseDecimal("1"), "")
+	if !errors.Is(err, ErrValidation) || replaced != 0 {
+		t.Errorf("ReplaceOrder error = %v, replaced = %d; want ErrValidation", err, replaced)
+	}
+	if _, err := client.Order.ReplaceOrder(ctx, "7524", MustParseDecimal("100.01"), MustParseDecimal("1"), ""); err != nil || replaced != 1 {
+		t.Errorf("ReplaceOrder error = %v, replaced = %d", err, replaced)
+	}
+
+	order := NewLimitOrder("BTC-AUD", SideAsk, MustParseDecimal("100.121"), MustParseDecimal("0.123456789"))
+	if err := client.MarketRules.RoundOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Price.String() != "100.13" || order.Amount.String() != "0.12345678" {
+		t.Errorf("RoundOrder = %s @ %s", order.Amount, order.Price)
+	}
+
+	if err := client.MarketRules.Refresh(ctx); err != nil || loads != 2 {
+		t.Errorf("Refresh error = %v, loads = %d", err, loads)
+	}
+}
@@ -0,0 +1,66 @@
+package btcmarkets
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestInfo describes a single attempt of an API call as seen by a
+// Middleware.
+type RequestInfo struct {
+	// Class of the endpoint targeted by the request
+	Class EndpointClass
+
+	// HTTP method of the request
+	Method string
+
+	// Authenticated is true for signed requests
+	Authenticated bool
+
+	// Attempt is 0 for the first attempt and counts retries after that
+	Attempt int
+
+	// Start is the time the HTTP round trip started
+	Start time.Time
+
+	// Duration of the HTTP round trip, set once the innermost handler
+	// returned
+	Duration time.Duration
+}
+
+// Handler performs the HTTP round trip of an API call. Responses with a
+// status code outside the 200 range are reported as an *ErrorResponse along
+// with the response; the response body of a successful call is left unread
+// for the client to decode.
+type Handler func(req *http.Request, info *RequestInfo) (*http.Response, error)
+
+// Middleware wraps a Handler to add behaviour around every HTTP round trip
+// made by the client, e.g. logging, latency measurement, header injection
+// or fault injection. Public and signed requests go through the same chain;
+// signed requests already carry their BM-AUTH headers when they reach it.
+type Middleware func(next Handler) Handler
+
+// chain wraps h with mw so that mw[0] is the outermost middleware.
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// send is the innermost Handler making the HTTP round trip.
+func (c *BTCMClient) send(req *http.Request, info *RequestInfo) (*http.Response, error) {
+	info.Start = time.Now()
+	resp, err := c.client.Do(req)
+	info.Duration = time.Since(info.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckResponse(resp)
+	if err != nil {
+		resp.Body.Close()
+		return resp, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,78 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/v3/accounts/me/balances", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Injected") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	var order []string
+	var infos []RequestInfo
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request, info *RequestInfo) (*http.Response, error) {
+				order = append(order, name)
+				resp, err := next(req, info)
+				infos = append(infos, *info)
+				return resp, err
+			}
+		}
+	}
+	inject := func(next Handler) Handler {
+		return func(req *http.Request, info *RequestInfo) (*http.Response, error) {
+			req.Header.Set("X-Injected", "yes")
+			return next(req, info)
+		}
+	}
+	client.handler = chain(client.send, []Middleware{record("outer"), inject, record("inner")})
+
+	_, err = client.Account.GetBalances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("Unexpected middleware order %v", order)
+	}
+	info := infos[1]
+	if info.Class != EndpointQuery || !info.Authenticated || info.Method != http.MethodGet || info.Start.IsZero() {
+		t.Errorf("Unexpected request info %+v", info)
+	}
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	client, _, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fault := errors.New("injected fault")
+	client.handler = chain(client.send, []Middleware{
+		func(next Handler) Handler {
+			return func(req *http.Request, info *RequestInfo) (*http.Response, error) {
+				return nil, fault
+			}
+		},
+	})
+
+	_, err = client.GetServerTime(context.Background())
+	if !errors.Is(err, fault) {
+		t.Errorf("Expected injected fault got %v", err)
+	}
+}
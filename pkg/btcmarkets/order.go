@@ -1,7 +1,9 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -11,37 +13,46 @@ import (
 
 // Order holds order information
 type Order struct {
-	Amount       string `json:"amount"`
-	CreationTime string `json:"creationTime"`
-	MarketID     string `json:"marketId"`
-	OpenAmount   string `json:"openAmount"`
-	OrderID      string `json:"orderId"`
-	Price        string `json:"price"`
-	Side         string `json:"side"`
-	Status       string `json:"status"`
-	Type         string `json:"type"`
+	Amount        Decimal     `json:"amount"`
+	CreationTime  string      `json:"creationTime"`
+	MarketID      string      `json:"marketId"`
+	OpenAmount    Decimal     `json:"openAmount"`
+	OrderID       string      `json:"orderId"`
+	Price         Decimal     `json:"price"`
+	Side          Side        `json:"side"`
+	Status        OrderStatus `json:"status"`
+	Type          OrderType   `json:"type"`
+	ClientOrderID string      `json:"clientOrderId"`
 }
 
 // OrderData stores data for new order created
 type OrderData struct {
-	OrderID      string    `json:"orderId"`
-	MarketID     string    `json:"marketId"`
-	Side         string    `json:"side"`
-	Type         string    `json:"type"`
-	CreationTime time.Time `json:"creationTime"`
-	Price        float64   `json:"price,string"`
-	Amount       float64   `json:"amount,string"`
-	OpenAmount   float64   `json:"openAmount,string"`
-	Status       string    `json:"status"`
+	OrderID       string      `json:"orderId"`
+	MarketID      string      `json:"marketId"`
+	Side          Side        `json:"side"`
+	Type          OrderType   `json:"type"`
+	CreationTime  time.Time   `json:"creationTime"`
+	Price         Decimal     `json:"price"`
+	Amount        Decimal     `json:"amount"`
+	OpenAmount    Decimal     `json:"openAmount"`
+	Status        OrderStatus `json:"status"`
+	ClientOrderID string      `json:"clientOrderId"`
 }
 
 // OrderPayload store data for the payload send to place new order
 type OrderPayload struct {
-	Amount   string `json:"amount"`
-	MarketID string `json:"marketId"`
-	Price    string `json:"price"`
-	Side     string `json:"side"`
-	Type     string `json:"type"`
+	Amount   Decimal   `json:"amount"`
+	MarketID string    `json:"marketId"`
+	Price    Decimal   `json:"price"`
+	Side     Side      `json:"side"`
+	Type     OrderType `json:"type"`
+}
+
+// ReplaceOrderPayload stores data for the payload sent to replace an order
+type ReplaceOrderPayload struct {
+	Price         Decimal `json:"price"`
+	Amount        Decimal `json:"amount"`
+	ClientOrderID string  `json:"clientOrderId,omitempty"`
 }
 
 // CancelOrderResp stores data for cancelled orders
@@ -61,9 +72,15 @@ type OrderServiceOp struct {
 // parameter is provided, this API retrieves open orders only for all markets.
 // This API supports pagination only when retrieving all orders status=all,
 // When sending using status=open all open orders are returned and with no pagination.
-func (o *OrderServiceOp) ListOrders(marketID, status string, before, after int64, limit int32) ([]Order, error) {
+// The returned Cursor holds the ids to pass as before or after to fetch the
+// adjacent pages; see also OrderIterator.
+func (o *OrderServiceOp) ListOrders(ctx context.Context, marketID, status string, before, after int64, limit int32) ([]Order, Cursor, error) {
 	var orders []Order
 
+	if before > 0 && after > 0 {
+		return nil, Cursor{}, errors.New("BTCMarkets only supports either before or after, not both")
+	}
+
 	params := url.Values{}
 	if marketID != "" {
 		params.Add("marketId", marketID)
@@ -81,21 +98,68 @@ func (o *OrderServiceOp) ListOrders(marketID, status string, before, after int64
 		params.Add("limit", strconv.Itoa(int(limit)))
 	}
 
-	req, err := o.client.NewRequest(http.MethodGet, path.Join(btcMarketsOrders, "?"+params.Encode()), nil)
+	req, err := o.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsOrders, "?"+params.Encode()), nil)
 	if err != nil {
-		return nil, err
+		return nil, Cursor{}, err
 	}
 
-	_, err = o.client.DoAuthenticated(req, nil, &orders)
+	resp, err := o.client.DoAuthenticated(req, nil, &orders)
 	if err != nil {
-		return nil, err
+		return nil, Cursor{}, err
+	}
+	return orders, cursorFromResponse(resp), nil
+}
+
+// OrderIterator walks the order history of the account, newest first,
+// fetching one page at a time.
+type OrderIterator struct {
+	pager
+	svc      *OrderServiceOp
+	marketID string
+	buf      []Order
+	cur      Order
+
+	// Stop, if set, ends the walk at the first order it returns true for.
+	Stop func(Order) bool
+}
+
+// IterateOrders returns an iterator over the orders of every status for
+// marketID, or for all markets when marketID is empty.
+func (o *OrderServiceOp) IterateOrders(ctx context.Context, marketID string, opts IteratorOptions) *OrderIterator {
+	return &OrderIterator{pager: newPager(ctx, opts), svc: o, marketID: marketID}
+}
+
+// Next advances to the next order and reports whether there is one.
+func (it *OrderIterator) Next() bool {
+	if it.done {
+		return false
 	}
-	return orders, nil
+	for len(it.buf) == 0 {
+		if !it.fetchPage(it.fetch) {
+			return false
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	t, _ := time.Parse(time.RFC3339Nano, it.cur.CreationTime)
+	return !it.stopAt(t, it.Stop != nil && it.Stop(it.cur))
+}
+
+// Order returns the current order.
+func (it *OrderIterator) Order() Order {
+	return it.cur
+}
+
+func (it *OrderIterator) fetch(after int64, limit int) (int, Cursor, error) {
+	var c Cursor
+	var err error
+	it.buf, c, err = it.svc.ListOrders(it.ctx, it.marketID, "all", 0, after, int32(limit))
+	return len(it.buf), c, err
 }
 
 // CancelOpenOrdersByPairs Cancels specified trading pairs for open orders for all markets or optionally
 //  for a given list of marketIds only.
-func (o *OrderServiceOp) CancelOpenOrdersByPairs(marketID []string) ([]CancelOrderResp, error) {
+func (o *OrderServiceOp) CancelOpenOrdersByPairs(ctx context.Context, marketID []string) ([]CancelOrderResp, error) {
 	var c []CancelOrderResp
 	params := url.Values{}
 
@@ -107,7 +171,7 @@ func (o *OrderServiceOp) CancelOpenOrdersByPairs(marketID []string) ([]CancelOrd
 		params.Add("marketId", marketID[i])
 	}
 
-	req, err := o.client.NewRequest(http.MethodDelete, path.Join(btcMarketsOrders, "?"+params.Encode()), nil)
+	req, err := o.client.NewRequest(ctx, http.MethodDelete, path.Join(btcMarketsOrders, "?"+params.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,10 +185,10 @@ func (o *OrderServiceOp) CancelOpenOrdersByPairs(marketID []string) ([]CancelOrd
 }
 
 // CancelAllOpenOrders Cancels all open orders
-func (o *OrderServiceOp) CancelAllOpenOrders() ([]CancelOrderResp, error) {
+func (o *OrderServiceOp) CancelAllOpenOrders(ctx context.Context) ([]CancelOrderResp, error) {
 	var c []CancelOrderResp
 
-	req, err := o.client.NewRequest(http.MethodDelete, path.Join(btcMarketsOrders), nil)
+	req, err := o.client.NewRequest(ctx, http.MethodDelete, path.Join(btcMarketsOrders), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -138,10 +202,10 @@ func (o *OrderServiceOp) CancelAllOpenOrders() ([]CancelOrderResp, error) {
 }
 
 // CancelOrder Cancels a single order. this API returns http error 400 if the order is realdy cancelled or matched, patrally matched.
-func (o *OrderServiceOp) CancelOrder(orderID string) (*CancelOrderResp, error) {
+func (o *OrderServiceOp) CancelOrder(ctx context.Context, orderID string) (*CancelOrderResp, error) {
 	var c CancelOrderResp
 
-	req, err := o.client.NewRequest(http.MethodDelete, path.Join(btcMarketsOrders, orderID), nil)
+	req, err := o.client.NewRequest(ctx, http.MethodDelete, path.Join(btcMarketsOrders, orderID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -155,10 +219,10 @@ func (o *OrderServiceOp) CancelOrder(orderID string) (*CancelOrderResp, error) {
 }
 
 // GetOrder Returns an order by using either the exachange `orderId` or `clientOrderId`
-func (o *OrderServiceOp) GetOrder(orderID string) (*Order, error) {
+func (o *OrderServiceOp) GetOrder(ctx context.Context, orderID string) (*Order, error) {
 	var or Order
 
-	req, err := o.client.NewRequest(http.MethodGet, path.Join(btcMarketsOrders, orderID), nil)
+	req, err := o.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsOrders, orderID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -171,47 +235,242 @@ func (o *OrderServiceOp) GetOrder(orderID string) (*Order, error) {
 	return &or, nil
 }
 
-// PlaceNewOrder This API is used to place a new order. Some of the parameteres are
-// mandatory as specified below. The right panel presents the default response with
-// primary attributes. You can also select from the drop down to see an order response '
-// with all possible order attributes.
-func (o *OrderServiceOp) PlaceNewOrder(marketID string, price, amount float64, orderType, side string,
-	triggerPrice, targetAmount float64, timeInForce string, postOnly bool, selfTrade, clientOrderID string) (OrderData, error) {
-
+// ReplaceOrder Replaces the price and amount of an open order in a single
+// call, keeping it in the orderbook instead of cancelling it and placing a
+// new one. orderID is either the exchange `orderId` or the `clientOrderId` of
+// the order; clientOrderID, when set, is assigned to the replacement order.
+// Errors match ErrOrderFinal when the order is already matched or cancelled.
+// When ClientConfig.EnforceMarketRules is set, the order is fetched first
+// with GetOrder to check the new price and amount against the rules of its
+// market, which costs one more request of the query class.
+func (o *OrderServiceOp) ReplaceOrder(ctx context.Context, orderID string, price, amount Decimal, clientOrderID string) (OrderData, error) {
 	var or OrderData
 
-	payload := make(map[string]interface{}, 10)
+	if orderID == "" {
+		return or, fmt.Errorf("btcmarkets: ReplaceOrder requires an order id: %w", ErrValidation)
+	}
+	if price.Sign() <= 0 || amount.Sign() <= 0 {
+		return or, fmt.Errorf("btcmarkets: replaced price and amount must be positive: %w", ErrValidation)
+	}
+	if o.client.enforceMarketRules {
+		current, err := o.GetOrder(ctx, orderID)
+		if err != nil {
+			return or, err
+		}
+		if err := o.client.MarketRules.validate(ctx, current.MarketID, &amount, &price); err != nil {
+			return or, err
+		}
+	}
+
+	payload := ReplaceOrderPayload{Price: price, Amount: amount, ClientOrderID: clientOrderID}
+	req, err := o.client.NewRequest(ctx, http.MethodPut, path.Join(btcMarketsOrders, orderID), payload)
+	if err != nil {
+		return or, err
+	}
+
+	_, err = o.client.DoAuthenticated(req, payload, &or)
+	if err != nil {
+		var er *ErrorResponse
+		if errors.As(err, &er) && er.Code == "InvalidOrderStatus" {
+			err = orderFinalError{err}
+		}
+		return or, err
+	}
+
+	return or, nil
+}
+
+// NewOrderRequest holds the parameters of a new order. It is encoded as the
+// exact payload expected by the place order API. Use one of NewLimitOrder,
+// NewMarketOrder, NewStopLimitOrder, NewStopOrder or NewTakeProfitOrder to
+// build it and the With methods to set the optional parameters.
+type NewOrderRequest struct {
+	MarketID      string      `json:"marketId"`
+	Price         *Decimal    `json:"price,omitempty"`
+	Amount        *Decimal    `json:"amount,omitempty"`
+	Type          OrderType   `json:"type"`
+	Side          Side        `json:"side"`
+	TriggerPrice  *Decimal    `json:"triggerPrice,omitempty"`
+	TargetAmount  *Decimal    `json:"targetAmount,omitempty"`
+	TimeInForce   TimeInForce `json:"timeInForce,omitempty"`
+	PostOnly      bool        `json:"postOnly,omitempty"`
+	SelfTrade     SelfTrade   `json:"selfTrade,omitempty"`
+	ClientOrderID string      `json:"clientOrderId,omitempty"`
+}
+
+// NewLimitOrder returns a request to buy or sell amount at price or better.
+func NewLimitOrder(marketID string, side Side, price, amount Decimal) *NewOrderRequest {
+	return &NewOrderRequest{MarketID: marketID, Type: OrderTypeLimit, Side: side, Price: &price, Amount: &amount}
+}
+
+// NewMarketOrder returns a request to buy or sell amount at the best
+// available price.
+func NewMarketOrder(marketID string, side Side, amount Decimal) *NewOrderRequest {
+	return &NewOrderRequest{MarketID: marketID, Type: OrderTypeMarket, Side: side, Amount: &amount}
+}
+
+// NewStopLimitOrder returns a request placing a limit order at price once
+// the market reaches triggerPrice.
+func NewStopLimitOrder(marketID string, side Side, price, triggerPrice, amount Decimal) *NewOrderRequest {
+	return &NewOrderRequest{MarketID: marketID, Type: OrderTypeStopLimit, Side: side, Price: &price, TriggerPrice: &triggerPrice, Amount: &amount}
+}
+
+// NewStopOrder returns a request placing a market order once the market
+// reaches triggerPrice.
+func NewStopOrder(marketID string, side Side, triggerPrice, amount Decimal) *NewOrderRequest {
+	return &NewOrderRequest{MarketID: marketID, Type: OrderTypeStop, Side: side, TriggerPrice: &triggerPrice, Amount: &amount}
+}
+
+// NewTakeProfitOrder returns a request placing a market order once the
+// market reaches triggerPrice, to lock in a profit.
+func NewTakeProfitOrder(marketID string, side Side, triggerPrice, amount Decimal) *NewOrderRequest {
+	return &NewOrderRequest{MarketID: marketID, Type: OrderTypeTakeProfit, Side: side, TriggerPrice: &triggerPrice, Amount: &amount}
+}
+
+// WithTimeInForce sets how long a limit order stays in the orderbook.
+func (r *NewOrderRequest) WithTimeInForce(t TimeInForce) *NewOrderRequest {
+	r.TimeInForce = t
+	return r
+}
+
+// WithPostOnly makes a limit order fail instead of matching on placement.
+func (r *NewOrderRequest) WithPostOnly() *NewOrderRequest {
+	r.PostOnly = true
+	return r
+}
+
+// WithSelfTrade sets whether the order may match orders of the same account.
+func (r *NewOrderRequest) WithSelfTrade(s SelfTrade) *NewOrderRequest {
+	r.SelfTrade = s
+	return r
+}
+
+// WithClientOrderID sets the id used to look the order up, and to safely
+// retry its placement.
+func (r *NewOrderRequest) WithClientOrderID(id string) *NewOrderRequest {
+	r.ClientOrderID = id
+	return r
+}
+
+// WithTargetAmount sets, for a market order, the amount of quote currency
+// to spend or receive instead of the amount of base currency, which is
+// removed from the request.
+func (r *NewOrderRequest) WithTargetAmount(amount Decimal) *NewOrderRequest {
+	r.TargetAmount = &amount
+	r.Amount = nil
+	return r
+}
+
+// Validate checks the request holds every field its order type requires and
+// none it does not accept. Market orders take either an amount or a target
+// amount, other orders an amount. Errors match ErrValidation.
+func (r *NewOrderRequest) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("btcmarkets: "+format+": %w", append(args, ErrValidation)...)
+	}
+
+	if r.MarketID == "" {
+		return invalid("market id is required")
+	}
+	if err := r.Type.Validate(); err != nil {
+		return err
+	}
+	if err := r.Side.Validate(); err != nil {
+		return err
+	}
+	if r.Amount != nil && r.Amount.Sign() <= 0 {
+		return invalid("amount must be positive")
+	}
+	if r.Amount == nil && (r.Type != OrderTypeMarket || r.TargetAmount == nil) {
+		return invalid("amount is required")
+	}
 
-	// TODO: Add validation for the list of mandatory fields
-	payload["marketId"] = marketID
-	payload["price"] = strconv.FormatFloat(price, 'f', -1, 64)
-	payload["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
-	payload["type"] = orderType
-	payload["side"] = side
+	if r.Type.RequiresPrice() {
+		if r.Price == nil || r.Price.Sign() <= 0 {
+			return invalid("%s orders require a positive price", r.Type)
+		}
+	} else if r.Price != nil {
+		return invalid("%s orders do not accept a price", r.Type)
+	}
+
+	if r.Type.RequiresTriggerPrice() {
+		if r.TriggerPrice == nil || r.TriggerPrice.Sign() <= 0 {
+			return invalid("%s orders require a positive trigger price", r.Type)
+		}
+	} else if r.TriggerPrice != nil {
+		return invalid("%s orders do not accept a trigger price", r.Type)
+	}
+
+	if r.TargetAmount != nil {
+		if r.Type != OrderTypeMarket {
+			return invalid("%s orders do not accept a target amount", r.Type)
+		}
+		if r.Amount != nil {
+			return invalid("market orders take either an amount or a target amount")
+		}
+		if r.TargetAmount.Sign() <= 0 {
+			return invalid("target amount must be positive")
+		}
+	}
 
-	if orderType == stopLimit || orderType == takeProfit || orderType == stop {
-		payload["triggerPrice"] = strconv.FormatFloat(triggerPrice, 'f', -1, 64)
+	if r.TimeInForce != "" {
+		if err := r.TimeInForce.Validate(); err != nil {
+			return err
+		}
+		if !r.Type.RequiresPrice() {
+			return invalid("%s orders do not accept a time in force", r.Type)
+		}
 	}
-	if targetAmount > 0 {
-		payload["targetAmount"] = strconv.FormatFloat(targetAmount, 'f', -1, 64)
+	if r.PostOnly {
+		if !r.Type.RequiresPrice() {
+			return invalid("%s orders cannot be post only", r.Type)
+		}
+		if r.TimeInForce == TimeInForceIOC || r.TimeInForce == TimeInForceFOK {
+			return invalid("post only orders cannot be %s", r.TimeInForce)
+		}
 	}
-	if timeInForce != "" {
-		payload["timeInForce"] = timeInForce
+	if r.SelfTrade != "" {
+		return r.SelfTrade.Validate()
 	}
-	payload["postOnly"] = postOnly
-	if selfTrade != "" {
-		payload["selfTrade"] = selfTrade
+	return nil
+}
+
+// PlaceNewOrder This API is used to place a new order. The request is
+// validated before it is sent, see NewOrderRequest.Validate. A clientOrderId
+// is generated when order has none, so the placement can be retried safely
+// and the order looked up when the outcome is unknown; see
+// PlaceNewOrderWithRecovery. order itself is left unchanged: the id sent is
+// returned in OrderData.ClientOrderID, also when the request fails.
+func (o *OrderServiceOp) PlaceNewOrder(ctx context.Context, order *NewOrderRequest) (OrderData, error) {
+	var or OrderData
+
+	if order == nil {
+		return or, errors.New("PlaceNewOrder requires an order")
+	}
+	if err := order.Validate(); err != nil {
+		return or, err
+	}
+	if o.client.enforceMarketRules {
+		if err := o.client.MarketRules.ValidateOrder(ctx, order); err != nil {
+			return or, err
+		}
 	}
-	if clientOrderID != "" {
-		payload["clientOrderID"] = clientOrderID
+
+	p := *order
+	if p.ClientOrderID == "" {
+		p.ClientOrderID = o.client.newClientOrderID()
 	}
+	or.ClientOrderID = p.ClientOrderID
 
-	req, err := o.client.NewRequest(http.MethodPost, btcMarketsOrders, payload)
+	req, err := o.client.NewRequest(ctx, http.MethodPost, btcMarketsOrders, &p)
 	if err != nil {
 		return or, err
 	}
 
-	_, err = o.client.DoAuthenticated(req, payload, &or)
+	_, err = o.client.DoAuthenticated(req, &p, &or)
+	if or.ClientOrderID == "" {
+		or.ClientOrderID = p.ClientOrderID
+	}
 	if err != nil {
 		return or, err
 	}
@@ -0,0 +1,198 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestNewOrderRequestValidate(t *testing.T) {
+	one := MustParseDecimal("1")
+
+	tests := []struct {
+		name  string
+		order *NewOrderRequest
+		valid bool
+	}{
+		{"Limit", NewLimitOrder("BTC-AUD", SideBid, one, one).WithTimeInForce(TimeInForceGTC).WithPostOnly(), true},
+		{"Market", NewMarketOrder("BTC-AUD", SideAsk, one).WithTargetAmount(one), true},
+		{"Stop limit", NewStopLimitOrder("BTC-AUD", SideAsk, one, one, one), true},
+		{"Stop", NewStopOrder("BTC-AUD", SideAsk, one, one), true},
+		{"Take profit", NewTakeProfitOrder("BTC-AUD", SideAsk, one, one).WithSelfTrade(SelfTradePrevent), true},
+		{"Missing market", NewMarketOrder("", SideAsk, one), false},
+		{"Zero amount", NewMarketOrder("BTC-AUD", SideAsk, Decimal{}), false},
+		{"Limit without price", &NewOrderRequest{MarketID: "BTC-AUD", Type: OrderTypeLimit, Side: SideBid, Amount: &one}, false},
+		{"Stop without trigger", &NewOrderRequest{MarketID: "BTC-AUD", Type: OrderTypeStop, Side: SideBid, Amount: &one}, false},
+		{"Market with price", &NewOrderRequest{MarketID: "BTC-AUD", Type: OrderTypeMarket, Side: SideBid, Amount: &one, Price: &one}, false},
+		{"Limit with target amount", NewLimitOrder("BTC-AUD", SideBid, one, one).WithTargetAmount(one), false},
+		{"Market with amount and target amount", &NewOrderRequest{MarketID: "BTC-AUD", Type: OrderTypeMarket, Side: SideBid, Amount: &one, TargetAmount: &one}, false},
+		{"Market without amount", &NewOrderRequest{MarketID: "BTC-AUD", Type: OrderTypeMarket, Side: SideBid}, false},
+		{"Market post only", NewMarketOrder("BTC-AUD", SideBid, one).WithPostOnly(), false},
+		{"Post only IOC", NewLimitOrder("BTC-AUD", SideBid, one, one).WithTimeInForce(TimeInForceIOC).WithPostOnly(), false},
+		{"Unknown self trade", NewLimitOrder("BTC-AUD", SideBid, one, one).WithSelfTrade("X"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.order.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrValidation) {
+				t.Errorf("Validate() error = %v; want ErrValidation", err)
+			}
+		})
+	}
+}
+
+func TestNewOrderRequestTargetAmount(t *testing.T) {
+	order := NewMarketOrder("BTC-AUD", SideBid, MustParseDecimal("1")).WithTargetAmount(MustParseDecimal("250.5"))
+	if err := order.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	b, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"marketId":"BTC-AUD","type":"Market","side":"Bid","targetAmount":"250.5"}`; string(b) != want {
+		t.Errorf("payload = %s; want %s", b, want)
+	}
+}
+
+func TestPlaceNewOrderPayload(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload map[string]interface{}
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Error(err)
+			return
+		}
+		w.Write([]byte(`{"orderId":"7524","marketId":"BTC-AUD","side":"Ask","type":"Stop Limit","price":"12000.5","amount":"0.01","status":"Accepted"}`))
+	})
+
+	order := NewStopLimitOrder("BTC-AUD", SideAsk, MustParseDecimal("12000.50"), MustParseDecimal("12100"), MustParseDecimal("0.01")).
+		WithClientOrderID("abc-1")
+	od, err := client.Order.PlaceNewOrder(context.Background(), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if od.OrderID != "7524" || od.Type != OrderTypeStopLimit {
+		t.Errorf("PlaceNewOrder = %+v", od)
+	}
+
+	want := map[string]interface{}{
+		"marketId":      "BTC-AUD",
+		"price":         "12000.50",
+		"amount":        "0.01",
+		"type":          "Stop Limit",
+		"side":          "Ask",
+		"triggerPrice":  "12100",
+		"clientOrderId": "abc-1",
+	}
+	if len(payload) != len(want) {
+		t.Errorf("payload = %v; want %v", payload, want)
+	}
+	for k, v := range want {
+		if payload[k] != v {
+			t.Errorf("payload[%q] = %v; want %v", k, payload[k], v)
+		}
+	}
+}
+
+func TestPlaceNewOrderKeepsRequest(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []string
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		var p NewOrderRequest
+		json.NewDecoder(r.Body).Decode(&p)
+		sent = append(sent, p.ClientOrderID)
+		w.Write([]byte(`{"orderId":"7524","marketId":"BTC-AUD","side":"Bid","type":"Limit","price":"1","amount":"1","status":"Accepted"}`))
+	})
+
+	order := NewLimitOrder("BTC-AUD", SideBid, MustParseDecimal("1"), MustParseDecimal("1"))
+	var returned []string
+	for i := 0; i < 2; i++ {
+		od, err := client.Order.PlaceNewOrder(context.Background(), order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		returned = append(returned, od.ClientOrderID)
+	}
+
+	if order.ClientOrderID != "" {
+		t.Errorf("request ClientOrderID = %q; want it unchanged", order.ClientOrderID)
+	}
+	if len(sent) != 2 || sent[0] == "" || sent[0] == sent[1] {
+		t.Errorf("sent clientOrderIds = %q; want 2 distinct ids", sent)
+	}
+	if len(returned) != 2 || returned[0] != sent[0] || returned[1] != sent[1] {
+		t.Errorf("returned clientOrderIds = %q; sent %q", returned, sent)
+	}
+}
+
+func TestReplaceOrder(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/v3/orders/7524", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s; want PUT", r.Method)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != `{"price":"12000.5","amount":"0.02","clientOrderId":"abc-2"}` {
+			t.Errorf("payload = %s", b)
+		}
+		w.Write([]byte(`{"orderId":"7525","marketId":"BTC-AUD","side":"Bid","type":"Limit","price":"12000.5","amount":"0.02","status":"Accepted"}`))
+	})
+	mux.HandleFunc("/v3/orders/7000", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"OrderAlreadyMatched","message":"order is already matched"}`))
+	})
+	mux.HandleFunc("/v3/orders/7001", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"InvalidOrderStatus","message":"invalid order status"}`))
+	})
+
+	ctx := context.Background()
+	od, err := client.Order.ReplaceOrder(ctx, "7524", MustParseDecimal("12000.5"), MustParseDecimal("0.02"), "abc-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if od.OrderID != "7525" || !od.Amount.Equal(MustParseDecimal("0.02")) {
+		t.Errorf("ReplaceOrder = %+v", od)
+	}
+
+	_, err = client.Order.ReplaceOrder(ctx, "7000", MustParseDecimal("1"), MustParseDecimal("1"), "")
+	if !errors.Is(err, ErrOrderFinal) {
+		t.Errorf("ReplaceOrder error = %v; want ErrOrderFinal", err)
+	}
+
+	_, err = client.Order.ReplaceOrder(ctx, "7001", MustParseDecimal("1"), MustParseDecimal("1"), "")
+	var er *ErrorResponse
+	if !errors.Is(err, ErrOrderFinal) || !errors.As(err, &er) || er.Code != "InvalidOrderStatus" {
+		t.Errorf("ReplaceOrder error = %v; want ErrOrderFinal", err)
+	}
+
+	_, err = client.Order.ReplaceOrder(ctx, "", MustParseDecimal("1"), MustParseDecimal("1"), "")
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("ReplaceOrder error = %v; want ErrValidation", err)
+	}
+}
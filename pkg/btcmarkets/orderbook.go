@@ -0,0 +1,522 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OrderbookLevel is a price level of an orderbook. It is encoded in JSON as
+// the [price, volume, count] array sent by BTCMarkets; the count is only
+// sent by the orderbookUpdate channel.
+type OrderbookLevel struct {
+	Price  Decimal
+	Volume Decimal
+	Count  int64
+}
+
+// UnmarshalJSON decodes a [price, volume] or [price, volume, count] array.
+func (l *OrderbookLevel) UnmarshalJSON(b []byte) error {
+	var v []json.RawMessage
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v) < 2 {
+		return fmt.Errorf("btcmarkets: invalid orderbook level %s", b)
+	}
+
+	*l = OrderbookLevel{}
+	if err := l.Price.UnmarshalJSON(v[0]); err != nil {
+		return err
+	}
+	if err := l.Volume.UnmarshalJSON(v[1]); err != nil {
+		return err
+	}
+	if len(v) > 2 {
+		c, err := strconv.ParseInt(strings.Trim(string(v[2]), `"`), 10, 64)
+		if err != nil {
+			return fmt.Errorf("btcmarkets: invalid orderbook level %s", b)
+		}
+		l.Count = c
+	}
+	return nil
+}
+
+// MarshalJSON encodes l as a [price, volume, count] array.
+func (l OrderbookLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{l.Price, l.Volume, l.Count})
+}
+
+// OrderbookChecksum computes the checksum of an orderbook from its bids,
+// best first, and its asks, best first.
+type OrderbookChecksum func(bids, asks []OrderbookLevel) uint32
+
+// DefaultOrderbookChecksum is the CRC32 (IEEE) of the top 10 bids followed
+// by the top 10 asks, each level written as its price then its volume with
+// the decimal point and the leading zeros removed.
+func DefaultOrderbookChecksum(bids, asks []OrderbookLevel) uint32 {
+	var sb strings.Builder
+	write := func(levels []OrderbookLevel) {
+		for i := 0; i < len(levels) && i < 10; i++ {
+			sb.WriteString(checksumDigits(levels[i].Price))
+			sb.WriteString(checksumDigits(levels[i].Volume))
+		}
+	}
+	write(bids)
+	write(asks)
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
+
+func checksumDigits(d Decimal) string {
+	return strings.TrimLeft(strings.Replace(d.String(), ".", "", 1), "0")
+}
+
+// LocalOrderbook is the L2 orderbook of a market maintained by an
+// OrderbookEngine. It is safe for concurrent use.
+type LocalOrderbook struct {
+	mu         sync.RWMutex
+	marketID   string
+	snapshotID int64
+	bids       []OrderbookLevel // best, i.e. highest, price first
+	asks       []OrderbookLevel // best, i.e. lowest, price first
+}
+
+// MarketID returns the market of the orderbook.
+func (b *LocalOrderbook) MarketID() string {
+	return b.marketID
+}
+
+// SnapshotID returns the id of the last snapshot or update applied.
+func (b *LocalOrderbook) SnapshotID() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.snapshotID
+}
+
+// BestBid returns the highest bid, and false if there is none.
+func (b *LocalOrderbook) BestBid() (OrderbookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.bids) == 0 {
+		return OrderbookLevel{}, false
+	}
+	return b.bids[0], true
+}
+
+// BestAsk returns the lowest ask, and false if there is none.
+func (b *LocalOrderbook) BestAsk() (OrderbookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.asks) == 0 {
+		return OrderbookLevel{}, false
+	}
+	return b.asks[0], true
+}
+
+// Bids returns up to depth bids, best first, or all of them when depth is 0.
+func (b *LocalOrderbook) Bids(depth int) []OrderbookLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return top(b.bids, depth)
+}
+
+// Asks returns up to depth asks, best first, or all of them when depth is 0.
+func (b *LocalOrderbook) Asks(depth int) []OrderbookLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return top(b.asks, depth)
+}
+
+// VolumeAt returns the volume offered on side at price or better, i.e. the
+// bids at price or higher or the asks at price or lower.
+func (b *LocalOrderbook) VolumeAt(side Side, price Decimal) Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	levels, better := b.asks, -1
+	if side == SideBid {
+		levels, better = b.bids, 1
+	}
+
+	var v Decimal
+	for _, l := range levels {
+		if c := l.Price.Cmp(price); c != 0 && c != better {
+			break
+		}
+		v = v.Add(l.Volume)
+	}
+	return v
+}
+
+func top(levels []OrderbookLevel, depth int) []OrderbookLevel {
+	if depth <= 0 || depth > len(levels) {
+		depth = len(levels)
+	}
+	return append([]OrderbookLevel(nil), levels[:depth]...)
+}
+
+// reset replaces the content of the orderbook with a snapshot.
+func (b *LocalOrderbook) reset(snapshotID int64, bids, asks []OrderbookLevel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.snapshotID = snapshotID
+	b.bids, b.asks = nil, nil
+	for _, l := range bids {
+		b.bids = setLevel(b.bids, l, true)
+	}
+	for _, l := range asks {
+		b.asks = setLevel(b.asks, l, false)
+	}
+}
+
+// update applies the levels of an incremental update.
+func (b *LocalOrderbook) update(snapshotID int64, bids, asks []OrderbookLevel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.snapshotID = snapshotID
+	for _, l := range bids {
+		b.bids = setLevel(b.bids, l, true)
+	}
+	for _, l := range asks {
+		b.asks = setLevel(b.asks, l, false)
+	}
+}
+
+// checksum returns the checksum of the orderbook computed with sum.
+func (b *LocalOrderbook) checksum(sum OrderbookChecksum) uint32 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return sum(b.bids, b.asks)
+}
+
+// setLevel inserts, replaces or, when its volume is zero, removes l in
+// levels, sorted by descending price when desc is set.
+func setLevel(levels []OrderbookLevel, l OrderbookLevel, desc bool) []OrderbookLevel {
+	i := sort.Search(len(levels), func(i int) bool {
+		c := levels[i].Price.Cmp(l.Price)
+		if desc {
+			return c <= 0
+		}
+		return c >= 0
+	})
+	found := i < len(levels) && levels[i].Price.Equal(l.Price)
+
+	switch {
+	case l.Volume.Sign() <= 0:
+		if found {
+			levels = append(levels[:i], levels[i+1:]...)
+		}
+	case found:
+		levels[i] = l
+	default:
+		levels = append(levels, OrderbookLevel{})
+		copy(levels[i+1:], levels[i:])
+		levels[i] = l
+	}
+	return levels
+}
+
+// OrderbookChange notifies a change of a LocalOrderbook.
+type OrderbookChange struct {
+	MarketID   string
+	SnapshotID int64
+
+	// Resynced is set when the orderbook was rebuilt from a snapshot rather
+	// than updated.
+	Resynced bool
+
+	// Bids and Asks hold the levels changed by an update; a zero volume
+	// marks a removed level.
+	Bids []OrderbookLevel
+	Asks []OrderbookLevel
+}
+
+// OrderbookOptions configures an OrderbookEngine.
+type OrderbookOptions struct {
+	// Checksum computes the checksum compared with the one published with
+	// every update. DefaultOrderbookChecksum is used when it is nil.
+	Checksum OrderbookChecksum
+
+	// SkipChecksum disables the checksum verification. As the checksum is
+	// the only way to detect a missed update, gaps then go unnoticed until
+	// the next reconnection.
+	SkipChecksum bool
+
+	// MinResyncInterval is the minimum time between two resyncs of the same
+	// orderbook. It doubles after every failed resync, up to a minute or
+	// MinResyncInterval if longer, and defaults to 1s.
+	MinResyncInterval time.Duration
+
+	// OnChange, if set, is called after every change of an orderbook, from
+	// the goroutine calling Apply or, for a resync, from the goroutine
+	// resyncing it.
+	OnChange func(OrderbookChange)
+}
+
+const (
+	defaultOrderbookResyncInterval = time.Second
+	maxOrderbookResyncInterval     = time.Minute
+	// maxOrderbookPending is the number of updates buffered during a
+	// resync, beyond which the resync is deemed outdated.
+	maxOrderbookPending = 1000
+)
+
+// OrderbookEngine maintains local L2 orderbooks from the events of a
+// WebSocket subscription to the orderbookUpdate channel. Each event is
+// passed to Apply by the consumer of the stream; updates are applied in
+// snapshotId order and stale ones are dropped.
+//
+// The snapshotIds of consecutive updates are not contiguous, so the checksum
+// published with every update is the only way to detect a missed one. An
+// orderbook is resynced from GetMarketOrderbook when its first event is not
+// a snapshot, when its checksum does not match the published one, and
+// after a reconnection. Resyncs run in the background, at most once per
+// MinResyncInterval for each market; the updates received meanwhile are
+// buffered and replayed on top of the fetched orderbook.
+type OrderbookEngine struct {
+	market *MarketServiceOp
+	logger Logger
+	opts   OrderbookOptions
+
+	mu    sync.Mutex
+	books map[string]*bookState
+	// resyncs tracks the background resyncs, for tests.
+	resyncs sync.WaitGroup
+}
+
+// bookState is the synchronisation state of an orderbook, guarded by the
+// mutex of the engine.
+type bookState struct {
+	book  *LocalOrderbook
+	stale bool
+
+	// resyncing is set while a resync runs; gen identifies it, and is
+	// bumped when a snapshot makes it outdated.
+	resyncing bool
+	gen       int
+	pending   []*BTCMWSOrderbookUpdateEvent
+
+	// next is when the next resync may start, and wait the interval to
+	// the following one.
+	next time.Time
+	wait time.Duration
+	err  error
+}
+
+// NewOrderbookEngine returns an engine resyncing orderbooks through the
+// market service of the client. opts may be nil for the defaults.
+func (ws *WebSocketServiceOp) NewOrderbookEngine(opts *OrderbookOptions) *OrderbookEngine {
+	e := &OrderbookEngine{
+		market: &ws.client.Market,
+		logger: ws.client.logger,
+		books:  make(map[string]*bookState),
+	}
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.Checksum == nil {
+		e.opts.Checksum = DefaultOrderbookChecksum
+	}
+	if e.opts.MinResyncInterval <= 0 {
+		e.opts.MinResyncInterval = defaultOrderbookResyncInterval
+	}
+	return e
+}
+
+// Book returns the orderbook of marketID, and false until it was built or
+// while it is being resynced.
+func (e *OrderbookEngine) Book(marketID string) (*LocalOrderbook, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st, ok := e.books[marketID]
+	if !ok || st.stale {
+		return nil, false
+	}
+	return st.book, true
+}
+
+// Apply updates the orderbooks from an event of the stream. Events other
+// than orderbook updates and reconnections are ignored. Resyncs run with
+// ctx, which must outlive the call. When the last resync of the market of
+// the event failed, its error is returned once; the resync is retried on a
+// later update.
+func (e *OrderbookEngine) Apply(ctx context.Context, ev WSEvent) error {
+	switch ev := ev.(type) {
+	case *WSReconnectEvent:
+		e.mu.Lock()
+		for _, st := range e.books {
+			st.stale = true
+		}
+		e.mu.Unlock()
+		return nil
+	case *BTCMWSOrderbookUpdateEvent:
+		return e.applyUpdate(ctx, ev)
+	}
+	return nil
+}
+
+func (e *OrderbookEngine) applyUpdate(ctx context.Context, ev *BTCMWSOrderbookUpdateEvent) error {
+	e.mu.Lock()
+	st, ok := e.books[ev.MarketID]
+	if !ok {
+		st = &bookState{book: &LocalOrderbook{marketID: ev.MarketID}, stale: true, wait: e.opts.MinResyncInterval}
+		e.books[ev.MarketID] = st
+	}
+	err := st.err
+	st.err = nil
+	b := st.book
+
+	switch {
+	case ev.Snapshot:
+		// A snapshot supersedes any resync in progress.
+		st.gen++
+		st.resyncing, st.pending = false, nil
+		b.reset(ev.SnapshotID, ev.Bids, ev.Asks)
+	case st.resyncing:
+		// Past maxOrderbookPending updates, the resync is given up.
+		if len(st.pending) <= maxOrderbookPending {
+			st.pending = append(st.pending, ev)
+		}
+		e.mu.Unlock()
+		return err
+	case st.stale:
+		e.startResync(ctx, st, ev)
+		e.mu.Unlock()
+		return err
+	case ev.SnapshotID <= b.SnapshotID():
+		e.mu.Unlock()
+		return err
+	default:
+		b.update(ev.SnapshotID, ev.Bids, ev.Asks)
+	}
+
+	if !e.checksumOK(b, ev) {
+		e.startResync(ctx, st, nil)
+		e.mu.Unlock()
+		return err
+	}
+	st.stale = false
+	e.mu.Unlock()
+
+	e.notify(OrderbookChange{MarketID: b.marketID, SnapshotID: ev.SnapshotID, Resynced: ev.Snapshot, Bids: ev.Bids, Asks: ev.Asks})
+	return err
+}
+
+// checksumOK reports whether b matches the checksum published with ev. A
+// checksum that cannot be parsed is a mismatch, so the book is resynced
+// rather than trusted.
+func (e *OrderbookEngine) checksumOK(b *LocalOrderbook, ev *BTCMWSOrderbookUpdateEvent) bool {
+	if e.opts.SkipChecksum || ev.Checksum == "" {
+		return true
+	}
+	want, err := strconv.ParseUint(ev.Checksum.String(), 10, 32)
+	if err != nil {
+		e.logger.Warn("invalid orderbook checksum", "marketId", b.marketID, "checksum", ev.Checksum.String(), "error", err)
+		return false
+	}
+	return b.checksum(e.opts.Checksum) == uint32(want)
+}
+
+// startResync marks st stale and, unless a resync ran too recently, starts
+// one in the background, replaying ev, if not nil, once it is done.
+// e.mu must be held.
+func (e *OrderbookEngine) startResync(ctx context.Context, st *bookState, ev *BTCMWSOrderbookUpdateEvent) {
+	st.stale = true
+	now := time.Now()
+	if now.Before(st.next) {
+		return
+	}
+
+	st.gen++
+	st.resyncing = true
+	st.pending = nil
+	if ev != nil {
+		st.pending = append(st.pending, ev)
+	}
+	st.next = now.Add(st.wait)
+
+	e.resyncs.Add(1)
+	go e.resync(ctx, st, st.gen)
+}
+
+// resync rebuilds the orderbook of st from the full orderbook returned by
+// the REST API, and replays the updates received meanwhile.
+func (e *OrderbookEngine) resync(ctx context.Context, st *bookState, gen int) {
+	defer e.resyncs.Done()
+
+	marketID := st.book.marketID
+	ob, err := e.market.GetMarketOrderbook(ctx, marketID, 2)
+
+	e.mu.Lock()
+	if st.gen != gen {
+		// A snapshot arrived meanwhile.
+		e.mu.Unlock()
+		return
+	}
+	st.resyncing = false
+	pending := st.pending
+	st.pending = nil
+
+	if err != nil {
+		st.err = fmt.Errorf("btcmarkets: resyncing the %s orderbook: %w", marketID, err)
+		if st.wait *= 2; st.wait > maxOrderbookResyncInterval {
+			st.wait = maxOrderbookResyncInterval
+		}
+		if st.wait < e.opts.MinResyncInterval {
+			st.wait = e.opts.MinResyncInterval
+		}
+		e.mu.Unlock()
+		e.logger.Warn("orderbook resync failed", "marketId", marketID, "error", err)
+		return
+	}
+	st.wait = e.opts.MinResyncInterval
+
+	b := st.book
+	b.reset(int64(ob.SnapshotID), levelsOf(ob.Bids), levelsOf(ob.Asks))
+	changes := []OrderbookChange{{MarketID: marketID, SnapshotID: int64(ob.SnapshotID), Resynced: true}}
+	synced := len(pending) <= maxOrderbookPending
+	for _, ev := range pending {
+		if !synced || ev.SnapshotID <= b.SnapshotID() {
+			continue
+		}
+		b.update(ev.SnapshotID, ev.Bids, ev.Asks)
+		if synced = e.checksumOK(b, ev); synced {
+			changes = append(changes, OrderbookChange{MarketID: marketID, SnapshotID: ev.SnapshotID, Bids: ev.Bids, Asks: ev.Asks})
+		}
+	}
+	st.stale = !synced
+	e.mu.Unlock()
+
+	if !synced {
+		e.logger.Warn("orderbook out of sync after resync", "marketId", marketID)
+		return
+	}
+	for _, c := range changes {
+		e.notify(c)
+	}
+}
+
+func (e *OrderbookEngine) notify(c OrderbookChange) {
+	if e.opts.OnChange != nil {
+		e.opts.OnChange(c)
+	}
+}
+
+// levelsOf converts the [price, volume, ...] levels of an OrderBook.
+func levelsOf(levels [][]Decimal) []OrderbookLevel {
+	out := make([]OrderbookLevel, 0, len(levels))
+	for _, l := range levels {
+		if len(l) >= 2 {
+			out = append(out, OrderbookLevel{Price: l[0], Volume: l[1]})
+		}
+	}
+	return out
+}
@@ -0,0 +1,255 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func levels(pv ...string) []OrderbookLevel {
+	var out []OrderbookLevel
+	for i := 0; i+1 < len(pv); i += 2 {
+		out = append(out, OrderbookLevel{Price: MustParseDecimal(pv[i]), Volume: MustParseDecimal(pv[i+1])})
+	}
+	return out
+}
+
+func checksumOf(bids, asks []OrderbookLevel) json.Number {
+	return json.Number(strconv.FormatUint(uint64(DefaultOrderbookChecksum(bids, asks)), 10))
+}
+
+func TestOrderbookLevelJSON(t *testing.T) {
+	var e BTCMWSOrderbookUpdateEvent
+	err := json.Unmarshal([]byte(`{"marketId":"BTC-AUD","snapshotId":1578512833978000,"bids":[["99.57","0.55",1]],"asks":[["99.65","0",0]],"checksum":"3614218150","messageType":"orderbookUpdate"}`), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := e.Bids[0]; l.Price.String() != "99.57" || l.Volume.String() != "0.55" || l.Count != 1 {
+		t.Errorf("Bids[0] = %+v", l)
+	}
+	if !e.Asks[0].Volume.IsZero() || e.Checksum != "3614218150" {
+		t.Errorf("event = %+v", e)
+	}
+	if err := json.Unmarshal([]byte(`{"checksum":3614218150}`), &e); err != nil || e.Checksum != "3614218150" {
+		t.Errorf("numeric checksum = %v, %v", e.Checksum, err)
+	}
+}
+
+func TestDefaultOrderbookChecksum(t *testing.T) {
+	// The vector is built by hand from the documented construction: the
+	// price then the volume of the top 10 bids and asks, without the
+	// decimal point and the leading zeros, i.e.
+	// "131005" "1309999123" "13050125" | "13200599" "132012", and its CRC32
+	// computed independently of the package (zlib.crc32 in Python).
+	const want = 1362575258
+	bids := levels("13100", "0.5", "13099.99", "0.0123", "13050", "1.25")
+	asks := levels("13200.5", "0.00099", "13201", "2")
+	if got := DefaultOrderbookChecksum(bids, asks); got != want {
+		t.Errorf("DefaultOrderbookChecksum = %d; want %d", got, want)
+	}
+
+	// Only the top 10 levels of each side count.
+	var deep []OrderbookLevel
+	for i := 0; i < 12; i++ {
+		deep = append(deep, levels(strconv.Itoa(100-i), "1")...)
+	}
+	if DefaultOrderbookChecksum(deep, nil) != DefaultOrderbookChecksum(deep[:10], nil) {
+		t.Error("levels beyond the top 10 changed the checksum")
+	}
+
+	// The same vector published with a snapshot is accepted without a
+	// resync.
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/v3/markets/BTC-AUD/orderbook", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the orderbook should not be resynced")
+	})
+	ev, err := decodeWSEvent([]byte(`{"marketId":"BTC-AUD","snapshot":true,"snapshotId":1578512833978000,` +
+		`"bids":[["13100","0.5",1],["13099.99","0.0123",2],["13050","1.25",1]],` +
+		`"asks":[["13200.5","0.00099",1],["13201","2",3]],"checksum":"1362575258","messageType":"orderbookUpdate"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := client.WebSocket.NewOrderbookEngine(nil)
+	if err := engine.Apply(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := engine.Book("BTC-AUD"); !ok {
+		t.Error("book should be built from the snapshot")
+	}
+}
+
+func TestOrderbookEngine(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resyncs int32
+	mux.HandleFunc("/v3/markets/BTC-AUD/orderbook", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&resyncs, 1)
+		w.Write([]byte(`{"marketId":"BTC-AUD","snapshotId":500,"bids":[["100","2"],["99","1"]],"asks":[["101","3"]]}`))
+	})
+
+	var changes []OrderbookChange
+	engine := client.WebSocket.NewOrderbookEngine(&OrderbookOptions{MinResyncInterval: time.Nanosecond, OnChange: func(c OrderbookChange) {
+		changes = append(changes, c)
+	}})
+	ctx := context.Background()
+
+	bids, asks := levels("100", "1", "99.5", "2"), levels("101", "1", "102", "4")
+	err = engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", Snapshot: true, SnapshotID: 10, Bids: bids, Asks: asks, Checksum: checksumOf(bids, asks)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, ok := engine.Book("BTC-AUD")
+	if !ok {
+		t.Fatal("book should be built from the snapshot")
+	}
+
+	// Remove 99.5, add 99.8, change 101 and drop a stale update.
+	bids, asks = levels("100", "1", "99.8", "3"), levels("101", "0.5", "102", "4")
+	err = engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: 11,
+		Bids: levels("99.5", "0", "99.8", "3"), Asks: levels("101", "0.5"), Checksum: checksumOf(bids, asks)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: 9, Bids: levels("1", "1")})
+
+	if b, _ := book.BestBid(); b.Price.String() != "100" {
+		t.Errorf("BestBid = %+v", b)
+	}
+	if a, _ := book.BestAsk(); a.Volume.String() != "0.5" {
+		t.Errorf("BestAsk = %+v", a)
+	}
+	if got := book.Bids(0); len(got) != 2 || got[1].Price.String() != "99.8" {
+		t.Errorf("Bids = %+v", got)
+	}
+	if v := book.VolumeAt(SideAsk, MustParseDecimal("102")); v.String() != "4.5" {
+		t.Errorf("VolumeAt(Ask, 102) = %s; want 4.5", v)
+	}
+	if v := book.VolumeAt(SideBid, MustParseDecimal("99.9")); v.String() != "1" {
+		t.Errorf("VolumeAt(Bid, 99.9) = %s; want 1", v)
+	}
+	if atomic.LoadInt32(&resyncs) != 0 || len(changes) != 2 || !changes[0].Resynced || changes[1].SnapshotID != 11 {
+		t.Errorf("resyncs = %d, changes = %+v", resyncs, changes)
+	}
+
+	// A checksum mismatch resyncs from the REST orderbook.
+	engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: 12, Bids: levels("100", "5"), Checksum: "1"})
+	engine.resyncs.Wait()
+	if atomic.LoadInt32(&resyncs) != 1 || book.SnapshotID() != 500 {
+		t.Fatalf("resyncs = %d, snapshot = %d", resyncs, book.SnapshotID())
+	}
+	if b, _ := book.BestBid(); b.Volume.String() != "2" {
+		t.Errorf("BestBid after resync = %+v", b)
+	}
+
+	// A checksum that cannot be parsed is a mismatch too.
+	time.Sleep(time.Millisecond)
+	engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: 501, Bids: levels("100", "5"), Checksum: "abc"})
+	engine.resyncs.Wait()
+	if b, _ := book.BestBid(); atomic.LoadInt32(&resyncs) != 2 || book.SnapshotID() != 500 || b.Volume.String() != "2" {
+		t.Fatalf("resyncs = %d, snapshot = %d, BestBid = %+v", resyncs, book.SnapshotID(), b)
+	}
+
+	// A reconnection makes the next update resync.
+	engine.Apply(ctx, &WSReconnectEvent{})
+	if _, ok := engine.Book("BTC-AUD"); ok {
+		t.Error("book should be stale after a reconnection")
+	}
+	bids, asks = levels("100", "2", "99", "1"), levels("101", "1")
+	time.Sleep(time.Millisecond)
+	engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: 501, Asks: levels("101", "1"), Checksum: checksumOf(bids, asks)})
+	engine.resyncs.Wait()
+	if _, ok := engine.Book("BTC-AUD"); !ok || atomic.LoadInt32(&resyncs) != 3 || book.SnapshotID() != 501 {
+		t.Errorf("resyncs = %d, snapshot = %d", resyncs, book.SnapshotID())
+	}
+}
+
+func TestOrderbookEngineResyncBudget(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resyncs int32
+	release := make(chan struct{})
+	mux.HandleFunc("/v3/markets/BTC-AUD/orderbook", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&resyncs, 1) > 1 {
+			http.Error(w, `{"code":"InternalServerError","message":"down"}`, http.StatusInternalServerError)
+			return
+		}
+		<-release
+		w.Write([]byte(`{"marketId":"BTC-AUD","snapshotId":500,"bids":[["100","2"]],"asks":[["101","3"]]}`))
+	})
+
+	engine := client.WebSocket.NewOrderbookEngine(&OrderbookOptions{MinResyncInterval: 20 * time.Second})
+	ctx := context.Background()
+	update := func(id int64, bids, asks []OrderbookLevel, checksum json.Number) error {
+		return engine.Apply(ctx, &BTCMWSOrderbookUpdateEvent{MarketID: "BTC-AUD", SnapshotID: id, Bids: bids, Asks: asks, Checksum: checksum})
+	}
+
+	// Updates received during the resync are buffered, not resynced again,
+	// and replayed once it is done, except those older than the snapshot.
+	update(499, levels("100", "9"), nil, "")
+	update(501, levels("99", "1"), nil, "")
+	update(502, nil, levels("101", "0", "102", "1"), checksumOf(levels("100", "2", "99", "1"), levels("102", "1")))
+	if _, ok := engine.Book("BTC-AUD"); ok {
+		t.Error("book should not be available during the resync")
+	}
+	close(release)
+	engine.resyncs.Wait()
+
+	book, ok := engine.Book("BTC-AUD")
+	if !ok || book.SnapshotID() != 502 || atomic.LoadInt32(&resyncs) != 1 {
+		t.Fatalf("after resync: ok = %v, resyncs = %d", ok, resyncs)
+	}
+	if b, _ := book.BestBid(); b.Volume.String() != "2" {
+		t.Errorf("BestBid = %+v", b)
+	}
+	if a, _ := book.BestAsk(); a.Price.String() != "102" {
+		t.Errorf("BestAsk = %+v", a)
+	}
+
+	// A mismatch within MinResyncInterval of the last resync skips the
+	// updates without calling the REST API.
+	for id := int64(503); id < 510; id++ {
+		if err := update(id, levels("100", "5"), nil, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine.resyncs.Wait()
+	if _, ok := engine.Book("BTC-AUD"); ok || atomic.LoadInt32(&resyncs) != 1 {
+		t.Errorf("after mismatches: ok = %v, resyncs = %d", ok, resyncs)
+	}
+
+	// A failed resync is reported once by Apply and backs off.
+	engine.mu.Lock()
+	engine.books["BTC-AUD"].next = time.Time{}
+	engine.mu.Unlock()
+	update(510, nil, nil, "")
+	engine.resyncs.Wait()
+	if err := update(511, nil, nil, ""); !errors.Is(err, ErrServer) {
+		t.Errorf("Apply error = %v; want ErrServer", err)
+	}
+	if err := update(512, nil, nil, ""); err != nil {
+		t.Errorf("Apply error = %v; want nil once reported", err)
+	}
+	engine.mu.Lock()
+	wait := engine.books["BTC-AUD"].wait
+	engine.mu.Unlock()
+	if wait != 40*time.Second {
+		t.Errorf("resync interval = %v after a failure", wait)
+	}
+}
@@ -0,0 +1,192 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOrderPollInterval = time.Second
+	// orderFeedPollInterval is how often WaitForOrder still polls while an
+	// orderChange feed of the market of the order is subscribed, in case the
+	// feed silently stalls.
+	orderFeedPollInterval = 30 * time.Second
+)
+
+// OrderCondition reports whether an order reached the state awaited by
+// WaitForOrder.
+type OrderCondition func(*Order) bool
+
+// OrderIsTerminal is the condition met once an order is Fully Matched,
+// Cancelled, Partially Cancelled or Failed.
+func OrderIsTerminal(o *Order) bool {
+	return o.Status.IsTerminal()
+}
+
+// WaitForOrder blocks until cond holds for the order identified by orderID,
+// either the exchange `orderId` or the `clientOrderId`, and returns the
+// order in that state. cond defaults to OrderIsTerminal when nil.
+//
+// While a WebSocket subscription to the orderChange channel of the market
+// of the order is open on the client, the order is only fetched again when a
+// change is published for it. Otherwise it is polled every ClientConfig.OrderPollInterval through
+// GetOrder, which waits on the rate limiter like every other request.
+func (o *OrderServiceOp) WaitForOrder(ctx context.Context, orderID string, cond OrderCondition) (*Order, error) {
+	if cond == nil {
+		cond = OrderIsTerminal
+	}
+
+	changed, stop := o.client.orderWatch.watch(orderID)
+	defer stop()
+
+	for {
+		or, err := o.GetOrder(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if cond(or) {
+			return or, nil
+		}
+		for _, id := range []string{or.OrderID, or.ClientOrderID} {
+			if id != "" && id != orderID {
+				o.client.orderWatch.add(changed, id)
+			}
+		}
+
+		wait := o.client.orderPollInterval
+		if o.client.orderWatch.active(or.MarketID) {
+			wait = orderFeedPollInterval
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-changed:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// orderWatchers dispatches the orderChange events received by the
+// WebSocket subscriptions of a client to the WaitForOrder calls watching
+// the changed orders.
+type orderWatchers struct {
+	mu sync.Mutex
+	// feeds counts the open orderChange subscriptions of every market, under
+	// "" for the subscriptions without markets, which cover all of them.
+	feeds    map[string]int
+	watchers map[string]map[chan struct{}]bool
+}
+
+// watch returns a channel signalled when the order id changes, and a
+// function to stop watching it.
+func (w *orderWatchers) watch(id string) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	w.add(ch, id)
+	return ch, func() { w.remove(ch) }
+}
+
+// add signals ch on the changes of one more order id.
+func (w *orderWatchers) add(ch chan struct{}, id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watchers == nil {
+		w.watchers = make(map[string]map[chan struct{}]bool)
+	}
+	if w.watchers[id] == nil {
+		w.watchers[id] = make(map[chan struct{}]bool)
+	}
+	w.watchers[id][ch] = true
+}
+
+func (w *orderWatchers) remove(ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, chans := range w.watchers {
+		delete(chans, ch)
+		if len(chans) == 0 {
+			delete(w.watchers, id)
+		}
+	}
+}
+
+// notify signals the watchers of the given order ids without blocking.
+func (w *orderWatchers) notify(ids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		for ch := range w.watchers[id] {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// feedUp and feedDown count the open subscriptions to the orderChange
+// channel of marketIDs, or of every market when marketIDs is empty.
+func (w *orderWatchers) feedUp(marketIDs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.feeds == nil {
+		w.feeds = make(map[string]int)
+	}
+	if len(marketIDs) == 0 {
+		w.feeds[""]++
+	}
+	for _, id := range marketIDs {
+		w.feeds[id]++
+	}
+}
+
+func (w *orderWatchers) feedDown(marketIDs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(marketIDs) == 0 {
+		marketIDs = []string{""}
+	}
+	for _, id := range marketIDs {
+		if w.feeds[id]--; w.feeds[id] <= 0 {
+			delete(w.feeds, id)
+		}
+	}
+}
+
+// active reports whether an orderChange subscription covering marketID is
+// open.
+func (w *orderWatchers) active(marketID string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.feeds[""] > 0 || w.feeds[marketID] > 0
+}
+
+// dispatch notifies the watchers of the order changed by payload, if it is
+// an orderChange event.
+func (w *orderWatchers) dispatch(payload []byte) {
+	var m struct {
+		MessageType   string          `json:"messageType"`
+		OrderID       json.RawMessage `json:"orderId"`
+		ClientOrderID string          `json:"clientOrderId"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil || m.MessageType != orderChange {
+		return
+	}
+
+	ids := []string{strings.Trim(string(m.OrderID), `"`)}
+	if m.ClientOrderID != "" {
+		ids = append(ids, m.ClientOrderID)
+	}
+	w.notify(ids...)
+}
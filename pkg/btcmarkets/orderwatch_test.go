@@ -0,0 +1,114 @@
+package btcmarkets
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWaitForOrderPolling(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.orderPollInterval = time.Millisecond
+
+	var polls int32
+	mux.HandleFunc("/v3/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		status := "Placed"
+		switch atomic.AddInt32(&polls, 1) {
+		case 1:
+		case 2:
+			status = "Partially Matched"
+		default:
+			status = "Cancelled"
+		}
+		w.Write([]byte(`{"orderId":"7524","clientOrderId":"abc","status":"` + status + `"}`))
+	})
+
+	ctx := context.Background()
+	o, err := client.Order.WaitForOrder(ctx, "abc", func(o *Order) bool {
+		return o.Status == OrderStatusPartiallyMatched
+	})
+	if err != nil || o.Status != OrderStatusPartiallyMatched {
+		t.Fatalf("WaitForOrder = %+v, %v", o, err)
+	}
+
+	o, err = client.Order.WaitForOrder(ctx, "abc", nil)
+	if err != nil || o.Status != OrderStatusCancelled {
+		t.Fatalf("WaitForOrder = %+v, %v", o, err)
+	}
+
+	mux.HandleFunc("/v3/orders/open", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orderId":"1","status":"Placed"}`))
+	})
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Order.WaitForOrder(ctx, "open", nil); err == nil {
+		t.Error("WaitForOrder should fail once the context expires")
+	}
+}
+
+func TestWaitForOrderWebSocket(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.orderPollInterval = time.Hour
+
+	var matched int32
+	fetched := make(chan struct{}, 10)
+	mux.HandleFunc("/v3/orders/7524", func(w http.ResponseWriter, r *http.Request) {
+		status := "Placed"
+		if atomic.LoadInt32(&matched) == 1 {
+			status = "Fully Matched"
+		}
+		w.Write([]byte(`{"orderId":"7524","marketId":"BTC-AUD","status":"` + status + `"}`))
+		fetched <- struct{}{}
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var m WSSubscribeMessage
+		c.ReadJSON(&m)
+
+		<-fetched
+		atomic.StoreInt32(&matched, 1)
+		c.WriteMessage(websocket.TextMessage, []byte(`{"orderId":7524,"marketId":"BTC-AUD","status":"Fully Matched","messageType":"orderChange"}`))
+		<-done
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := client.WebSocket.Subscribe(ctx, WSSubscribeMessage{Channels: []string{orderChange}, MarketIds: []string{"BTC-AUD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+	if !client.orderWatch.active("BTC-AUD") {
+		t.Fatal("orderChange feed should be active")
+	}
+	if client.orderWatch.active("ETH-AUD") {
+		t.Error("orderChange feed should not cover ETH-AUD")
+	}
+
+	o, err := client.Order.WaitForOrder(ctx, "7524", nil)
+	if err != nil || o.Status != OrderStatusFullyMatched {
+		t.Fatalf("WaitForOrder = %+v, %v", o, err)
+	}
+}
@@ -0,0 +1,101 @@
+package btcmarkets
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Cursor holds the pagination ids returned by BTCMarkets in the BM-BEFORE
+// and BM-AFTER headers of a paginated response. Records are returned newest
+// first: pass After as the `after` parameter to fetch the next, older, page
+// and Before as the `before` parameter to fetch newer records.
+type Cursor struct {
+	Before int64
+	After  int64
+}
+
+// cursorFromResponse reads the pagination headers of resp.
+func cursorFromResponse(resp *http.Response) Cursor {
+	var c Cursor
+	if resp == nil {
+		return c
+	}
+	c.Before, _ = strconv.ParseInt(resp.Header.Get("BM-BEFORE"), 10, 64)
+	c.After, _ = strconv.ParseInt(resp.Header.Get("BM-AFTER"), 10, 64)
+	return c
+}
+
+// IteratorOptions bounds the history walked by an iterator.
+type IteratorOptions struct {
+	// Limit is the page size requested from the API. The API default is
+	// used when it is 0.
+	Limit int
+
+	// Since stops the walk at the first record created before it. The
+	// whole history is walked when it is zero.
+	Since time.Time
+
+	// MaxPages caps the number of pages fetched. There is no cap when it
+	// is 0.
+	MaxPages int
+}
+
+// pager walks the pages of a paginated endpoint from the most recent record
+// backwards. Pages are fetched lazily, one at a time, through the client so
+// every request waits on the rate limiter of its endpoint class.
+type pager struct {
+	ctx   context.Context
+	opts  IteratorOptions
+	after int64
+	pages int
+	last  bool
+	done  bool
+	err   error
+}
+
+func newPager(ctx context.Context, opts IteratorOptions) pager {
+	return pager{ctx: ctx, opts: opts}
+}
+
+// fetchPage fetches the next page with fetch, which returns the number of
+// records in the page and its cursor. It returns false once the walk is over
+// or failed.
+func (p *pager) fetchPage(fetch func(after int64, limit int) (int, Cursor, error)) bool {
+	if p.done || p.last || (p.opts.MaxPages > 0 && p.pages >= p.opts.MaxPages) {
+		p.done = true
+		return false
+	}
+
+	n, c, err := fetch(p.after, p.opts.Limit)
+	p.pages++
+	if err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+	if n == 0 {
+		p.done = true
+		return false
+	}
+	if c.After == 0 || c.After == p.after {
+		p.last = true
+	}
+	p.after = c.After
+	return true
+}
+
+// stopAt ends the walk when t is older than the Since bound or stop is true,
+// and reports whether the walk is over.
+func (p *pager) stopAt(t time.Time, stop bool) bool {
+	if stop || (!p.opts.Since.IsZero() && t.Before(p.opts.Since)) {
+		p.done = true
+	}
+	return p.done
+}
+
+// Err returns the error that ended the walk, if any.
+func (p *pager) Err() error {
+	return p.err
+}
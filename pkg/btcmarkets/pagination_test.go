@@ -0,0 +1,74 @@
+package btcmarkets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTradeIterator(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trades 10 down to 1, newest first, one hour apart, in pages of 3.
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pages := 0
+	mux.HandleFunc("/v3/trades", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		start := 10
+		if a := r.URL.Query().Get("after"); a != "" {
+			fmt.Sscan(a, &start)
+			start--
+		}
+		if r.URL.Query().Get("limit") != "3" {
+			t.Errorf("Expected limit=3 got %s", r.URL.RawQuery)
+		}
+
+		body := "["
+		last := 0
+		for id := start; id > 0 && id > start-3; id-- {
+			if id != start {
+				body += ","
+			}
+			ts := base.Add(time.Duration(id) * time.Hour).Format(time.RFC3339)
+			body += fmt.Sprintf(`{"id":"%d","marketId":"BTC-AUD","timestamp":"%s","price":"100","amount":"1","fee":"0"}`, id, ts)
+			last = id
+		}
+		body += "]"
+		if last > 0 {
+			w.Header().Set("BM-BEFORE", fmt.Sprint(start))
+			w.Header().Set("BM-AFTER", fmt.Sprint(last))
+		}
+		w.Write([]byte(body))
+	})
+
+	it := client.Trade.IterateTrades(context.Background(), "BTC-AUD", IteratorOptions{Limit: 3})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Trade().ID)
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if len(ids) != 10 || ids[0] != "10" || ids[9] != "1" {
+		t.Errorf("Unexpected trades %v", ids)
+	}
+
+	pages = 0
+	it = client.Trade.IterateTrades(context.Background(), "BTC-AUD", IteratorOptions{Limit: 3, Since: base.Add(5 * time.Hour)})
+	ids = ids[:0]
+	for it.Next() {
+		ids = append(ids, it.Trade().ID)
+	}
+	if len(ids) != 6 || ids[5] != "5" {
+		t.Errorf("Expected trades 10 to 5 got %v", ids)
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages to be fetched got %d", pages)
+	}
+}
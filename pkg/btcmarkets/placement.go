@@ -0,0 +1,187 @@
+package btcmarkets
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultOrderLookupAttempts = 3
+	defaultOrderLookupInterval = time.Second
+	orderLookupTimeout         = 10 * time.Second
+)
+
+// NewClientOrderID returns a random version 4 UUID, suitable as the
+// clientOrderId of an order.
+func NewClientOrderID() string {
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		panic(fmt.Sprintf("btcmarkets: cannot generate a client order id: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// PlacementOutcome tells whether an order reached the exchange.
+type PlacementOutcome int
+
+// Placement outcomes
+const (
+	// PlacementPlaced means the exchange accepted the order.
+	PlacementPlaced PlacementOutcome = iota
+	// PlacementRejected means the order was not placed, either because it
+	// failed local validation, because the exchange refused it or because
+	// every lookup of an order whose placement failed found nothing.
+	PlacementRejected
+	// PlacementUnknown means the request failed in a way that does not tell
+	// whether the order was placed, and the order could not be found by its
+	// clientOrderId either.
+	PlacementUnknown
+)
+
+func (p PlacementOutcome) String() string {
+	switch p {
+	case PlacementPlaced:
+		return "Placed"
+	case PlacementRejected:
+		return "Rejected"
+	case PlacementUnknown:
+		return "Unknown"
+	}
+	return fmt.Sprintf("PlacementOutcome(%d)", int(p))
+}
+
+// PlacementResult is the outcome of PlaceNewOrderWithRecovery.
+type PlacementResult struct {
+	Outcome PlacementOutcome
+
+	// ClientOrderID identifies the order, whether it was given by the
+	// caller or generated by the client.
+	ClientOrderID string
+
+	// Order holds the order when it was placed.
+	Order OrderData
+
+	// Recovered is true when the order was found through GetOrder after
+	// the placement request failed.
+	Recovered bool
+}
+
+// PlaceNewOrderWithRecovery places order like PlaceNewOrder and always
+// tells the caller whether it reached the exchange. When the placement
+// request fails without a definite answer, e.g. it timed out or the
+// exchange returned a 5xx error, the order is looked up by its
+// clientOrderId, which is generated when unset, up to
+// ClientConfig.OrderLookupAttempts times. The same goes when the request
+// was retried, since the attempt that failed may have placed the order and
+// made the exchange refuse the retry, e.g. as a duplicate.
+//
+// The error is nil only when the outcome is PlacementPlaced. The lookups
+// stop when ctx is done, with PlacementUnknown. When ctx is already done as
+// the placement fails, which may be the reason it failed, the lookups run
+// regardless for at most 10 seconds in total.
+func (o *OrderServiceOp) PlaceNewOrderWithRecovery(ctx context.Context, order *NewOrderRequest) (PlacementResult, error) {
+	var res PlacementResult
+
+	placeCtx, trace := withRetryTrace(ctx)
+	od, err := o.PlaceNewOrder(placeCtx, order)
+	res.ClientOrderID = od.ClientOrderID
+	if err == nil {
+		res.Outcome = PlacementPlaced
+		res.Order = od
+		return res, nil
+	}
+	if res.ClientOrderID == "" || (trace.retries == 0 && !outcomeUnknown(err)) {
+		res.Outcome = PlacementRejected
+		return res, err
+	}
+
+	o.client.logger.Warn("order placement outcome unknown, looking the order up", "clientOrderId", res.ClientOrderID, "error", err)
+
+	lookupCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(detachedContext{ctx}, orderLookupTimeout)
+		defer cancel()
+	}
+
+	notFound := 0
+	for attempt := 1; attempt <= o.client.orderLookupAttempts; attempt++ {
+		if attempt > 1 {
+			if sleepContext(lookupCtx, o.client.orderLookupInterval) != nil {
+				break
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(lookupCtx, orderLookupTimeout)
+		found, lerr := o.GetOrder(attemptCtx, res.ClientOrderID)
+		cancel()
+		if lerr == nil {
+			res.Outcome = PlacementPlaced
+			res.Order = found.orderData()
+			res.Recovered = true
+			return res, nil
+		}
+		if errors.Is(lerr, ErrNotFound) {
+			notFound++
+		}
+		o.client.logger.Debug("order lookup failed", "clientOrderId", res.ClientOrderID, "attempt", attempt, "error", lerr)
+	}
+
+	// The order is only deemed rejected when every lookup positively found
+	// nothing, not when some of them failed or were cut short.
+	res.Outcome = PlacementUnknown
+	if notFound == o.client.orderLookupAttempts {
+		res.Outcome = PlacementRejected
+	}
+	return res, err
+}
+
+// outcomeUnknown reports whether a placement failing with err may still
+// have reached the exchange.
+func outcomeUnknown(err error) bool {
+	var er *ErrorResponse
+	if errors.As(err, &er) {
+		return er.StatusCode >= 500
+	}
+
+	var ue *url.Error
+	var ne net.Error
+	return errors.As(err, &ue) || errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// orderData converts an order returned by GetOrder into the OrderData
+// returned by PlaceNewOrder.
+func (o Order) orderData() OrderData {
+	t, _ := time.Parse(time.RFC3339Nano, o.CreationTime)
+	return OrderData{
+		OrderID:       o.OrderID,
+		MarketID:      o.MarketID,
+		Side:          o.Side,
+		Type:          o.Type,
+		CreationTime:  t,
+		Price:         o.Price,
+		Amount:        o.Amount,
+		OpenAmount:    o.OpenAmount,
+		Status:        o.Status,
+		ClientOrderID: o.ClientOrderID,
+	}
+}
+
+// detachedContext carries the values of its parent but is never cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
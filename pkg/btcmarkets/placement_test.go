@@ -0,0 +1,165 @@
+package btcmarkets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewClientOrderID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := NewClientOrderID(), NewClientOrderID()
+	if !uuid.MatchString(a) || a == b {
+		t.Errorf("NewClientOrderID() = %q, %q", a, b)
+	}
+}
+
+func TestPlaceNewOrderWithRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		place     int
+		lookup    int
+		want      PlacementOutcome
+		recovered bool
+		lookups   int
+	}{
+		{"Placed", http.StatusOK, http.StatusOK, PlacementPlaced, false, 0},
+		{"Rejected", http.StatusBadRequest, http.StatusOK, PlacementRejected, false, 0},
+		{"Recovered", http.StatusGatewayTimeout, http.StatusOK, PlacementPlaced, true, 1},
+		{"Not found", http.StatusInternalServerError, http.StatusNotFound, PlacementRejected, false, 2},
+		{"Unknown", http.StatusInternalServerError, http.StatusServiceUnavailable, PlacementUnknown, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mux, _, teardown, err := setup(nil)
+			defer teardown()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.retryPolicy = nil
+			client.orderLookupAttempts = 2
+			client.orderLookupInterval = time.Millisecond
+
+			var clientOrderID string
+			mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+				var p map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&p)
+				clientOrderID, _ = p["clientOrderId"].(string)
+
+				w.WriteHeader(tt.place)
+				if tt.place == http.StatusOK {
+					w.Write([]byte(`{"orderId":"1","marketId":"BTC-AUD","side":"Bid","type":"Market","status":"Accepted"}`))
+				} else {
+					w.Write([]byte(`{"code":"Error","message":"failed"}`))
+				}
+			})
+			lookups := 0
+			mux.HandleFunc("/v3/orders/", func(w http.ResponseWriter, r *http.Request) {
+				lookups++
+				if id := strings.TrimPrefix(r.URL.Path, "/v3/orders/"); id != clientOrderID {
+					t.Errorf("looked up %q; want %q", id, clientOrderID)
+				}
+				w.WriteHeader(tt.lookup)
+				if tt.lookup == http.StatusOK {
+					w.Write([]byte(`{"orderId":"1","marketId":"BTC-AUD","side":"Bid","type":"Market","status":"Fully Matched","creationTime":"2019-08-30T11:08:21.956000Z"}`))
+				} else if tt.lookup == http.StatusNotFound {
+					w.Write([]byte(`{"code":"OrderNotFound","message":"order not found"}`))
+				}
+			})
+
+			res, err := client.Order.PlaceNewOrderWithRecovery(context.Background(), NewMarketOrder("BTC-AUD", SideBid, MustParseDecimal("1")))
+			if res.Outcome != tt.want || res.Recovered != tt.recovered || lookups != tt.lookups {
+				t.Errorf("outcome = %v, recovered = %v, lookups = %d; want %v, %v, %d", res.Outcome, res.Recovered, lookups, tt.want, tt.recovered, tt.lookups)
+			}
+			if (err == nil) != (tt.want == PlacementPlaced) {
+				t.Errorf("error = %v", err)
+			}
+			if res.ClientOrderID == "" || res.ClientOrderID != clientOrderID {
+				t.Errorf("ClientOrderID = %q; sent %q", res.ClientOrderID, clientOrderID)
+			}
+			if tt.recovered && res.Order.Status != OrderStatusFullyMatched {
+				t.Errorf("Order = %+v", res.Order)
+			}
+		})
+	}
+}
+
+func TestPlaceNewOrderWithRecoveryAfterRetry(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.client.Timeout = 50 * time.Millisecond
+	client.orderLookupInterval = time.Millisecond
+
+	var posts int32
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&posts, 1) == 1 {
+			// The order is placed but the response arrives too late.
+			time.Sleep(200 * time.Millisecond)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"InvalidClientOrderId","message":"duplicate client order id"}`))
+	})
+	mux.HandleFunc("/v3/orders/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orderId":"1","marketId":"BTC-AUD","side":"Bid","type":"Market","status":"Placed"}`))
+	})
+
+	res, err := client.Order.PlaceNewOrderWithRecovery(context.Background(), NewMarketOrder("BTC-AUD", SideBid, MustParseDecimal("1")))
+	if err != nil || res.Outcome != PlacementPlaced || !res.Recovered || res.Order.OrderID != "1" {
+		t.Errorf("result = %+v, error = %v; want the order recovered", res, err)
+	}
+	if n := atomic.LoadInt32(&posts); n != 2 {
+		t.Errorf("placement sent %d times; want 2", n)
+	}
+}
+
+func TestPlaceNewOrderWithRecoveryCancelled(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.orderLookupInterval = time.Hour
+
+	mux.HandleFunc("/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	mux.HandleFunc("/v3/orders/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"OrderNotFound","message":"order not found"}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := client.Order.PlaceNewOrderWithRecovery(ctx, NewMarketOrder("BTC-AUD", SideBid, MustParseDecimal("1")))
+	if res.Outcome != PlacementUnknown || err == nil {
+		t.Errorf("outcome = %v, error = %v; want Unknown", res.Outcome, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("PlaceNewOrderWithRecovery returned after %v; want it to stop with ctx", d)
+	}
+}
+
+func TestPlaceNewOrderWithRecoveryRejectsInvalidOrder(t *testing.T) {
+	client, _, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Order.PlaceNewOrderWithRecovery(context.Background(), NewMarketOrder("BTC-AUD", SideBid, Decimal{}))
+	if res.Outcome != PlacementRejected || !errors.Is(err, ErrValidation) {
+		t.Errorf("outcome = %v, error = %v", res.Outcome, err)
+	}
+}
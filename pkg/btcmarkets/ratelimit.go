@@ -0,0 +1,196 @@
+package btcmarkets
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// EndpointClass groups the API endpoints sharing a rate limit bucket on
+// BTCMarkets.
+type EndpointClass int
+
+const (
+	// EndpointPublic covers unauthenticated market data endpoints
+	EndpointPublic EndpointClass = iota
+	// EndpointQuery covers authenticated read-only endpoints e.g. balances,
+	// order and trade history
+	EndpointQuery
+	// EndpointOrder covers placing, replacing and cancelling orders
+	EndpointOrder
+	// EndpointBatch covers the batch order endpoints
+	EndpointBatch
+	// EndpointWithdraw covers withdrawal requests
+	EndpointWithdraw
+)
+
+// EndpointClasses lists every EndpointClass.
+var EndpointClasses = []EndpointClass{
+	EndpointPublic,
+	EndpointQuery,
+	EndpointOrder,
+	EndpointBatch,
+	EndpointWithdraw,
+}
+
+// String returns the name of the endpoint class.
+func (e EndpointClass) String() string {
+	switch e {
+	case EndpointPublic:
+		return "public"
+	case EndpointQuery:
+		return "query"
+	case EndpointOrder:
+		return "order"
+	case EndpointBatch:
+		return "batch"
+	case EndpointWithdraw:
+		return "withdraw"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultRateLimits returns a new set of limiters matching the limits
+// published by BTCMarkets for each endpoint class.
+func DefaultRateLimits() map[EndpointClass]*rate.Limiter {
+	every := func(n int) *rate.Limiter {
+		return rate.NewLimiter(rate.Every(btcmarketsRateWindow/time.Duration(n)), n)
+	}
+
+	return map[EndpointClass]*rate.Limiter{
+		EndpointPublic:   every(btcmarketsPublicLimit),
+		EndpointQuery:    every(btcmarketsQueryLimit),
+		EndpointOrder:    every(btcmarketsOrderLimit),
+		EndpointBatch:    every(btcmarketsBatchLimit),
+		EndpointWithdraw: every(btcmarketsWithdrawLimit),
+	}
+}
+
+// RateBudget is a snapshot of the rate limit bucket of an endpoint class.
+type RateBudget struct {
+	Class EndpointClass
+
+	// Limit is the rate at which the bucket is refilled
+	Limit rate.Limit
+
+	// Burst is the capacity of the bucket
+	Burst int
+
+	// Available is the number of requests that can be made right now
+	// without waiting
+	Available int
+}
+
+// Limiter returns the rate limiter used for requests of the given class.
+func (c *BTCMClient) Limiter(class EndpointClass) *rate.Limiter {
+	return c.limiters[class]
+}
+
+// RateBudget returns the remaining budget of the given endpoint class.
+// Available is tracked from the requests the client made through the
+// limiter, without touching it, so it does not account for other users of a
+// limiter shared outside the client and is only a hint when requests are
+// made concurrently.
+func (c *BTCMClient) RateBudget(class EndpointClass) RateBudget {
+	l := c.Limiter(class)
+	b := RateBudget{Class: class}
+	if l == nil {
+		return b
+	}
+
+	b.Limit = l.Limit()
+	b.Burst = l.Burst()
+	b.Available = c.meters.get(l).available(l, time.Now())
+	return b
+}
+
+// rateMeters tracks the buckets of the limiters used by a client. Limiters
+// shared by several endpoint classes share their meter.
+type rateMeters struct {
+	mu     sync.Mutex
+	meters map[*rate.Limiter]*rateMeter
+}
+
+func (m *rateMeters) get(l *rate.Limiter) *rateMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.meters == nil {
+		m.meters = make(map[*rate.Limiter]*rateMeter)
+	}
+	rm, ok := m.meters[l]
+	if !ok {
+		rm = &rateMeter{}
+		m.meters[l] = rm
+	}
+	return rm
+}
+
+// rateMeter mirrors the token bucket of a limiter, refilled at its limit up
+// to its burst and drained by the requests the client let through it.
+// rate.Limiter offers no way to read its tokens without reserving them.
+type rateMeter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last call. m.mu must be held.
+func (m *rateMeter) refill(l *rate.Limiter, now time.Time) {
+	burst := float64(l.Burst())
+	if m.last.IsZero() {
+		m.tokens = burst
+	} else if elapsed := now.Sub(m.last).Seconds(); elapsed > 0 {
+		if l.Limit() == rate.Inf {
+			m.tokens = burst
+		} else {
+			m.tokens += elapsed * float64(l.Limit())
+		}
+	}
+	if m.tokens > burst {
+		m.tokens = burst
+	}
+	if now.After(m.last) {
+		m.last = now
+	}
+}
+
+// take records a request let through the limiter at now.
+func (m *rateMeter) take(l *rate.Limiter, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refill(l, now)
+	if m.tokens--; m.tokens < 0 {
+		m.tokens = 0
+	}
+}
+
+// available returns the number of whole tokens in the bucket at now.
+func (m *rateMeter) available(l *rate.Limiter, now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refill(l, now)
+	return int(m.tokens)
+}
+
+// endpointClass returns the class of the endpoint targeted by req.
+func (c *BTCMClient) endpointClass(req *http.Request, authenticated bool) EndpointClass {
+	if !authenticated {
+		return EndpointPublic
+	}
+
+	p := strings.TrimPrefix(req.URL.Path, c.BaseURL.Path)
+	switch {
+	case strings.HasPrefix(p, btcMarketsBatchOrders):
+		return EndpointBatch
+	case strings.HasPrefix(p, btcMarketsOrders) && req.Method != http.MethodGet:
+		return EndpointOrder
+	case strings.HasPrefix(p, btcMarketsWithdrawals) && req.Method == http.MethodPost:
+		return EndpointWithdraw
+	default:
+		return EndpointQuery
+	}
+}
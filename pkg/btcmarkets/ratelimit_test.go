@@ -0,0 +1,75 @@
+package btcmarkets
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestEndpointClass(t *testing.T) {
+	client, _, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		auth   bool
+		want   EndpointClass
+	}{
+		{http.MethodGet, btcMarketsAllMarkets, false, EndpointPublic},
+		{http.MethodGet, btcMarketsAccountBalance, true, EndpointQuery},
+		{http.MethodGet, btcMarketsOrders, true, EndpointQuery},
+		{http.MethodPost, btcMarketsOrders, true, EndpointOrder},
+		{http.MethodDelete, btcMarketsOrders + "/1234", true, EndpointOrder},
+		{http.MethodPost, btcMarketsBatchOrders, true, EndpointBatch},
+		{http.MethodGet, btcMarketsBatchOrders + "/1,2", true, EndpointBatch},
+		{http.MethodPost, btcMarketsWithdrawals, true, EndpointWithdraw},
+		{http.MethodGet, btcMarketsWithdrawals, true, EndpointQuery},
+	}
+
+	for _, tt := range tests {
+		req, err := client.NewRequest(context.Background(), tt.method, tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := client.endpointClass(req, tt.auth); got != tt.want {
+			t.Errorf("%s %s: want %v got %v", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestRateLimitsPerClass(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"timestamp": "2019-09-01T18:34:27.045000Z"}`))
+	})
+
+	client.limiters[EndpointOrder] = rate.NewLimiter(rate.Every(time.Hour), 1)
+	for i := 0; i < 3; i++ {
+		if b := client.RateBudget(EndpointOrder); b.Available != 1 || b.Burst != 1 {
+			t.Errorf("Expected full order budget got %+v", b)
+		}
+	}
+	if !client.Limiter(EndpointOrder).Allow() {
+		t.Error("Expected RateBudget to leave the order limiter untouched")
+	}
+
+	before := client.RateBudget(EndpointPublic)
+	if _, err := client.GetServerTime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	after := client.RateBudget(EndpointPublic)
+	if after.Available != before.Available-1 {
+		t.Errorf("Expected public budget to drop by 1, before %+v after %+v", before, after)
+	}
+}
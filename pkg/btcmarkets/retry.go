@@ -0,0 +1,229 @@
+package btcmarkets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries requests that failed with a
+// transient error: a connection error, a 5xx response or a 429 response.
+// GET requests are always eligible for a retry. POST, PUT and DELETE
+// requests are only retried when their payload carries a clientOrderId,
+// which makes them idempotent on the exchange side.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	// A value of 0 disables retries.
+	MaxRetries int
+
+	// MinBackoff is the wait before the first retry. It doubles on every
+	// subsequent retry until MaxBackoff is reached.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration
+
+	// OnRetry, if set, is called before the client waits for the next
+	// attempt. It can be used to log or count retries.
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a retry about to be made by the client.
+type RetryEvent struct {
+	// Request that failed
+	Request *http.Request
+
+	// Attempt is the number of the retry about to be made, starting at 1
+	Attempt int
+
+	// Err is the error returned by the failed attempt
+	Err error
+
+	// Wait is the backoff the client waits before the next attempt
+	Wait time.Duration
+}
+
+// DefaultRetryPolicy returns a RetryPolicy retrying up to 3 times with an
+// exponential backoff between 250ms and 5s.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries: 3,
+		MinBackoff: 250 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+	}
+}
+
+// backoff returns the wait before retry number attempt (starting at 1).
+// The exponential delay is jittered to spread retries from concurrent
+// callers, and a Retry-After header sent by the server takes precedence,
+// within MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if d, ok := retryAfter(err); ok {
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			d = p.MaxBackoff
+		}
+		return d
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// Equal jitter: wait between d/2 and d.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter extracts the delay requested by the Retry-After header of a
+// throttled or unavailable response.
+func retryAfter(err error) (time.Duration, bool) {
+	var er *ErrorResponse
+	if !errors.As(err, &er) || er.Response == nil {
+		return 0, false
+	}
+
+	v := er.Response.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryTrace counts the retries made for the requests sent with a context
+// returned by withRetryTrace.
+type retryTrace struct {
+	retries int
+}
+
+type retryTraceKey struct{}
+
+// withRetryTrace returns a copy of ctx recording the retries of its
+// requests in the returned retryTrace.
+func withRetryTrace(ctx context.Context) (context.Context, *retryTrace) {
+	t := &retryTrace{}
+	return context.WithValue(ctx, retryTraceKey{}, t), t
+}
+
+// isTransient reports whether err is worth retrying.
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var er *ErrorResponse
+	if errors.As(err, &er) {
+		return er.StatusCode == http.StatusTooManyRequests || er.StatusCode >= 500
+	}
+
+	// Certificate and TLS failures, and malformed URLs, are wrapped in a
+	// *url.Error like network failures but do not go away on a retry.
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalidCert      x509.CertificateInvalidError
+		hostname         x509.HostnameError
+		recordHeader     tls.RecordHeaderError
+	)
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostname) || errors.As(err, &recordHeader) {
+		return false
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) {
+		var op *net.OpError
+		return ne.Timeout() || errors.As(err, &op)
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// isIdempotent reports whether a request with the given method and JSON
+// body can safely be sent more than once.
+func isIdempotent(method string, body []byte) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+	if len(body) == 0 {
+		return false
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return false
+	}
+	return carriesClientOrderID(v)
+}
+
+// carriesClientOrderID reports whether every order instruction in the
+// decoded payload v has a clientOrderId. Single key objects such as the
+// {"placeOrder": {...}} wrappers used by batch orders are looked through.
+func carriesClientOrderID(v interface{}) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if id, ok := t["clientOrderId"].(string); ok && id != "" {
+			return true
+		}
+		if len(t) == 1 {
+			for _, inner := range t {
+				return carriesClientOrderID(inner)
+			}
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			return false
+		}
+		for _, e := range t {
+			if !carriesClientOrderID(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
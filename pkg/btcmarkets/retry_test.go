@@ -0,0 +1,173 @@
+package btcmarkets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryTransientGet(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"timestamp": "2019-09-01T18:34:27.045000Z"}`))
+		}
+	})
+
+	var retries []RetryEvent
+	client.retryPolicy = &RetryPolicy{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		OnRetry:    func(e RetryEvent) { retries = append(retries, e) },
+	}
+
+	st, err := client.GetServerTime(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Timestamp == "" {
+		t.Error("Expected a timestamp")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls got %d", calls)
+	}
+	if len(retries) != 2 || !errors.Is(retries[1].Err, ErrRateLimited) {
+		t.Errorf("Unexpected retry events %+v", retries)
+	}
+}
+
+func TestRetryNonIdempotentPost(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	mux.HandleFunc("/v3/withdrawals", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client.retryPolicy = &RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond}
+
+	req, err := client.NewRequest(context.Background(), http.MethodPost, btcMarketsWithdrawals, map[string]interface{}{"amount": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.DoAuthenticated(req, nil, nil)
+	if !errors.Is(err, ErrServer) {
+		t.Errorf("Expected ErrServer got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single call got %d", calls)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{http.MethodGet, ``, true},
+		{http.MethodDelete, ``, false},
+		{http.MethodPost, `{"marketId":"BTC-AUD"}`, false},
+		{http.MethodPost, `{"marketId":"BTC-AUD","clientOrderId":"abc"}`, true},
+		{http.MethodPost, `[{"placeOrder":{"clientOrderId":"a"}},{"cancelOrder":{"clientOrderId":"b"}}]`, true},
+		{http.MethodPost, `[{"placeOrder":{"clientOrderId":"a"}},{"cancelOrder":{"orderId":"1"}}]`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isIdempotent(tt.method, []byte(tt.body)); got != tt.want {
+			t.Errorf("isIdempotent(%s, %s) = %v want %v", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultRetryPolicyIsUsed(t *testing.T) {
+	client, err := NewBTCMClient(ClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := client.retryPolicy; p == nil || p.MaxRetries != DefaultRetryPolicy().MaxRetries {
+		t.Errorf("retry policy = %+v; want DefaultRetryPolicy", p)
+	}
+
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	mux.HandleFunc("/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client.retryPolicy = &RetryPolicy{MaxRetries: 0}
+	if _, err := client.GetServerTime(context.Background()); !errors.Is(err, ErrServer) || calls != 1 {
+		t.Errorf("with MaxRetries 0: error = %v after %d calls; want ErrServer after 1", err, calls)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"3600"}}}
+	err := &ErrorResponse{Response: resp, StatusCode: resp.StatusCode}
+
+	p := &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Second}
+	if d := p.backoff(1, err); d != 5*time.Second {
+		t.Errorf("backoff = %v; want MaxBackoff", d)
+	}
+	p.MaxBackoff = 0
+	if d := p.backoff(1, err); d != time.Hour {
+		t.Errorf("backoff without MaxBackoff = %v; want 1h", d)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	ctx := context.Background()
+	urlErr := func(err error) error { return &url.Error{Op: "Get", URL: "https://api.btcmarkets.net", Err: err} }
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Server error", &ErrorResponse{StatusCode: http.StatusBadGateway}, true},
+		{"Throttled", &ErrorResponse{StatusCode: http.StatusTooManyRequests}, true},
+		{"Bad request", &ErrorResponse{StatusCode: http.StatusBadRequest}, false},
+		{"Connection refused", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), true},
+		{"Timeout", urlErr(&net.DNSError{Err: "timeout", IsTimeout: true}), true},
+		{"Unexpected EOF", urlErr(io.ErrUnexpectedEOF), true},
+		{"Unknown authority", urlErr(x509.UnknownAuthorityError{}), false},
+		{"Invalid hostname", urlErr(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}), false},
+		{"TLS record header", urlErr(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), false},
+		{"Unsupported scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransient(ctx, tt.err); got != tt.want {
+			t.Errorf("%s: isTransient = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
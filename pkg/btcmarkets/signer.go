@@ -0,0 +1,132 @@
+package btcmarkets
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// Signer signs the messages used to authenticate REST requests and
+// WebSocket subscriptions. Implementations must return the base64 encoded
+// HMAC-SHA512 of message keyed with the API secret, and be safe for
+// concurrent use.
+type Signer interface {
+	Sign(ctx context.Context, message string) (string, error)
+}
+
+// HMACSigner signs messages in-process with the decoded API secret.
+type HMACSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a Signer holding the given base64 encoded API secret
+// in memory.
+func NewHMACSigner(secret string) (*HMACSigner, error) {
+	k, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, errors.New("Error Decoding APISecret")
+	}
+	return &HMACSigner{key: k}, nil
+}
+
+// Sign returns the base64 encoded HMAC-SHA512 of message.
+func (s *HMACSigner) Sign(ctx context.Context, message string) (string, error) {
+	h := hmac.New(sha512.New, s.key)
+	h.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// signRequest is sent to a signing daemon, one JSON document per line.
+type signRequest struct {
+	Message string `json:"message"`
+}
+
+// signResponse is returned by a signing daemon, one JSON document per line.
+type signResponse struct {
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// UnixSocketSigner delegates signing to a local daemon listening on a Unix
+// socket, so that the trading process never holds the API secret. For every
+// message a connection is opened and the line {"message": "..."} is written;
+// the daemon answers with the line {"signature": "..."} or {"error": "..."}.
+// ServeSigner implements the daemon side of this protocol.
+type UnixSocketSigner struct {
+	// Path of the Unix socket the daemon listens on
+	Path string
+
+	// Timeout bounds a signing round trip when ctx has no earlier
+	// deadline. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Sign asks the signing daemon to sign message.
+func (s *UnixSocketSigner) Sign(ctx context.Context, message string) (string, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", s.Path)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+
+	err = json.NewEncoder(conn).Encode(signRequest{Message: message})
+	if err != nil {
+		return "", err
+	}
+
+	var resp signResponse
+	err = json.NewDecoder(bufio.NewReader(conn)).Decode(&resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", errors.New("signer: " + resp.Error)
+	}
+	return resp.Signature, nil
+}
+
+// ServeSigner accepts connections on l and answers the signing requests of
+// UnixSocketSigner with s. It is meant to run in a separate signing process
+// holding the secret, and returns when l is closed.
+func ServeSigner(l net.Listener, s Signer) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+			var req signRequest
+			var resp signResponse
+			if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+				resp.Error = err.Error()
+			} else if sig, err := s.Sign(context.Background(), req.Message); err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Signature = sig
+			}
+			json.NewEncoder(conn).Encode(resp)
+		}(conn)
+	}
+}
@@ -0,0 +1,43 @@
+package btcmarkets
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "btcmarkets-signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "signer.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	defer l.Close()
+
+	hs, err := NewHMACSigner("TXlTdXBlclNlY3JldEtleQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ServeSigner(l, hs)
+
+	msg := "GET/v3/accounts/me/trading-fees1257894000000"
+	want, _ := hs.Sign(context.Background(), msg)
+
+	s := &UnixSocketSigner{Path: sock}
+	got, err := s.Sign(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("UnixSocketSigner.Sign() wanted = '%v' got = '%v'", want, got)
+	}
+}
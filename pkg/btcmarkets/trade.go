@@ -1,6 +1,7 @@
 package btcmarkets
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
@@ -14,10 +15,10 @@ type TradeHistoryData struct {
 	ID            string    `json:"id"`
 	MarketID      string    `json:"marketId"`
 	Timestamp     time.Time `json:"timestamp"`
-	Price         float64   `json:"price,string"`
-	Amount        float64   `json:"amount,string"`
-	Side          string    `json:"side"`
-	Fee           float64   `json:"fee,string"`
+	Price         Decimal   `json:"price"`
+	Amount        Decimal   `json:"amount"`
+	Side          Side      `json:"side"`
+	Fee           Decimal   `json:"fee"`
 	OrderID       string    `json:"orderId"`
 	LiquidityType string    `json:"liquidityType"`
 }
@@ -28,12 +29,14 @@ type TradeHistoryServiceOp struct {
 	client *BTCMClient
 }
 
-// ListTrades returns trade history
-func (th *TradeHistoryServiceOp) ListTrades(marketID, orderID string, before, after, limit int64) ([]TradeHistoryData, error) {
+// ListTrades returns trade history. This API supports pagination; the
+// returned Cursor holds the ids to pass as before or after to fetch the
+// adjacent pages; see also TradeIterator.
+func (th *TradeHistoryServiceOp) ListTrades(ctx context.Context, marketID, orderID string, before, after, limit int64) ([]TradeHistoryData, Cursor, error) {
 	var resp []TradeHistoryData
 
-	if (before > 0) && (after >= 0) {
-		return resp, errors.New("BTCMarkets only supports either before or after, not both")
+	if (before > 0) && (after > 0) {
+		return resp, Cursor{}, errors.New("BTCMarkets only supports either before or after, not both")
 	}
 
 	params := url.Values{}
@@ -47,31 +50,77 @@ func (th *TradeHistoryServiceOp) ListTrades(marketID, orderID string, before, af
 	if before > 0 {
 		params.Set("before", strconv.FormatInt(before, 10))
 	}
-	if after >= 0 {
+	if after > 0 {
 		params.Set("after", strconv.FormatInt(after, 10))
 	}
 	if limit > 0 {
 		params.Set("limit", strconv.FormatInt(limit, 10))
 	}
 
-	req, err := th.client.NewRequest(http.MethodGet, path.Join(btcMarketsTradeHistory, "?"+params.Encode()), nil)
+	req, err := th.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsTradeHistory, "?"+params.Encode()), nil)
 	if err != nil {
-		return resp, err
+		return resp, Cursor{}, err
 	}
 
-	_, err = th.client.DoAuthenticated(req, nil, &resp)
+	r, err := th.client.DoAuthenticated(req, nil, &resp)
 	if err != nil {
-		return nil, err
+		return nil, Cursor{}, err
 	}
 
-	return resp, nil
+	return resp, cursorFromResponse(r), nil
+}
+
+// TradeIterator walks the trade history of the account, newest first,
+// fetching one page at a time.
+type TradeIterator struct {
+	pager
+	svc      *TradeHistoryServiceOp
+	marketID string
+	buf      []TradeHistoryData
+	cur      TradeHistoryData
+
+	// Stop, if set, ends the walk at the first trade it returns true for.
+	Stop func(TradeHistoryData) bool
+}
+
+// IterateTrades returns an iterator over the trades of the account for
+// marketID, or for all markets when marketID is empty.
+func (th *TradeHistoryServiceOp) IterateTrades(ctx context.Context, marketID string, opts IteratorOptions) *TradeIterator {
+	return &TradeIterator{pager: newPager(ctx, opts), svc: th, marketID: marketID}
+}
+
+// Next advances to the next trade and reports whether there is one.
+func (it *TradeIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for len(it.buf) == 0 {
+		if !it.fetchPage(it.fetch) {
+			return false
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return !it.stopAt(it.cur.Timestamp, it.Stop != nil && it.Stop(it.cur))
+}
+
+// Trade returns the current trade.
+func (it *TradeIterator) Trade() TradeHistoryData {
+	return it.cur
+}
+
+func (it *TradeIterator) fetch(after int64, limit int) (int, Cursor, error) {
+	var c Cursor
+	var err error
+	it.buf, c, err = it.svc.ListTrades(it.ctx, it.marketID, "", 0, after, int64(limit))
+	return len(it.buf), c, err
 }
 
 // GetTradeByID returns the singular trade of the ID given
-func (th *TradeHistoryServiceOp) GetTradeByID(id string) (TradeHistoryData, error) {
+func (th *TradeHistoryServiceOp) GetTradeByID(ctx context.Context, id string) (TradeHistoryData, error) {
 	var resp TradeHistoryData
 
-	req, err := th.client.NewRequest(http.MethodGet, path.Join(btcMarketsTradeHistory, id), nil)
+	req, err := th.client.NewRequest(ctx, http.MethodGet, path.Join(btcMarketsTradeHistory, id), nil)
 	if err != nil {
 		return resp, err
 	}
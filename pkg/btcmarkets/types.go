@@ -43,31 +43,25 @@ const (
 	btcMarketsReports        = "/reports"
 	btcMarketsBatchOrders    = "/batchorders"
 
-	btcmarketsAuthLimit   = 3
-	btcmarketsUnauthLimit = 50
-
-	orderFailed             = "Failed"
-	orderPartiallyCancelled = "Partially Cancelled"
-	orderCancelled          = "Cancelled"
-	orderFullyMatched       = "Fully Matched"
-	orderPartiallyMatched   = "Partially Matched"
-	orderPlaced             = "Placed"
-	orderAccepted           = "Accepted"
-
-	ask        = "ask"
-	limit      = "Limit"
-	market     = "Market"
-	stopLimit  = "Stop Limit"
-	stop       = "Stop"
-	takeProfit = "Take Profit"
-
-	subscribe   = "subscribe"
-	fundChange  = "fundChange"
-	orderChange = "orderChange"
-	heartbeat   = "heartbeat"
-	tick        = "tick"
-	wsOB        = "orderbook"
-	trade       = "trade"
+	// Requests allowed per btcmarketsRateWindow for each EndpointClass
+	btcmarketsRateWindow    = 10 * time.Second
+	btcmarketsPublicLimit   = 50
+	btcmarketsQueryLimit    = 50
+	btcmarketsOrderLimit    = 30
+	btcmarketsBatchLimit    = 5
+	btcmarketsWithdrawLimit = 10
+
+	subscribe          = "subscribe"
+	addSubscription    = "addSubscription"
+	removeSubscription = "removeSubscription"
+	fundChange         = "fundChange"
+	orderChange        = "orderChange"
+	heartbeat          = "heartbeat"
+	tick               = "tick"
+	wsOB               = "orderbook"
+	wsOBUpdate         = "orderbookUpdate"
+	wsError            = "error"
+	trade              = "trade"
 )
 
 // tempOrderbook stores orderbook data
@@ -95,11 +89,11 @@ type tempOrderbook struct {
 // MarketCandle stores candle data for a given pair
 type MarketCandle struct {
 	Time   time.Time
-	Open   float64
-	Close  float64
-	Low    float64
-	High   float64
-	Volume float64
+	Open   Decimal
+	Close  Decimal
+	Low    Decimal
+	High   Decimal
+	Volume Decimal
 }
 
 // TimeResp stores server time
@@ -112,8 +106,8 @@ type TradingFee struct {
 	Success        bool    `json:"success"`
 	ErrorCode      int     `json:"errorCode"`
 	ErrorMessage   string  `json:"errorMessage"`
-	TradingFeeRate float64 `json:"tradingfeerate"`
-	Volume30Day    float64 `json:"volume30day"`
+	TradingFeeRate Decimal `json:"tradingfeerate"`
+	Volume30Day    Decimal `json:"volume30day"`
 }
 
 // OrderToGo holds order information to be sent to the exchange
@@ -149,17 +143,17 @@ type TradeResponse struct {
 	ID           int64     `json:"id"`
 	CreationTime time.Time `json:"creationTime"`
 	Description  string    `json:"description"`
-	Price        float64   `json:"price"`
-	Volume       float64   `json:"volume"`
-	Fee          float64   `json:"fee"`
+	Price        Decimal   `json:"price"`
+	Volume       Decimal   `json:"volume"`
+	Fee          Decimal   `json:"fee"`
 }
 
 // AccountData stores account data
 type AccountData struct {
 	AssetName string  `json:"assetName"`
-	Balance   float64 `json:"balance,string"`
-	Available float64 `json:"available,string"`
-	Locked    float64 `json:"locked,string"`
+	Balance   Decimal `json:"balance"`
+	Available Decimal `json:"available"`
+	Locked    Decimal `json:"locked"`
 }
 
 // CancelOrderResp stores data for cancelled orders
@@ -1,7 +1,7 @@
 package btcmarkets
 
 import (
-	"fmt"
+	"encoding/json"
 	"strconv"
 	"time"
 
@@ -13,63 +13,65 @@ import (
 // bestBid or bestAsk is updated for a market which is the result of
 // orderbook changes or trade matches.
 type BTCMWSTickEvent struct {
-	BestAsk     string `json:"bestAsk"`
-	BestBid     string `json:"bestBid"`
-	LastPrice   string `json:"lastPrice"`
-	MarketID    string `json:"marketId"`
-	MessageType string `json:"messageType"`
-	Timestamp   string `json:"timestamp"`
-	Volume24h   string `json:"volume24h"`
+	BestAsk     Decimal `json:"bestAsk"`
+	BestBid     Decimal `json:"bestBid"`
+	LastPrice   Decimal `json:"lastPrice"`
+	MarketID    string  `json:"marketId"`
+	MessageType string  `json:"messageType"`
+	Timestamp   string  `json:"timestamp"`
+	Volume24h   Decimal `json:"volume24h"`
 }
 
 // BTCMWSTradeEvent In order to receive trade events please add trade to
 // the list of channels when subscribing via WebSocket.
 type BTCMWSTradeEvent struct {
-	MarketID    string `json:"marketId"`
-	MessageType string `json:"messageType"`
-	Price       string `json:"price"`
-	Side        string `json:"side"`
-	Timestamp   string `json:"timestamp"`
-	TradeID     int64  `json:"tradeId"`
-	Volume      string `json:"volume"`
+	MarketID    string  `json:"marketId"`
+	MessageType string  `json:"messageType"`
+	Price       Decimal `json:"price"`
+	Side        Side    `json:"side"`
+	Timestamp   string  `json:"timestamp"`
+	TradeID     int64   `json:"tradeId"`
+	Volume      Decimal `json:"volume"`
 }
 
 // BTCMWSOrderbookEvent In order to receive orderbook events please add orderbook to
 // the list of channels when subscribing via WebSocket. The current orderbook event represents
 // the latest orderbook state and maximum 50 bids and asks are included in each event.
 type BTCMWSOrderbookEvent struct {
-	Asks        [][]string `json:"asks"`
-	Bids        [][]string `json:"bids"`
-	MarketID    string     `json:"marketId"`
-	MessageType string     `json:"messageType"`
-	Timestamp   string     `json:"timestamp"`
+	Asks        [][]Decimal `json:"asks"`
+	Bids        [][]Decimal `json:"bids"`
+	MarketID    string      `json:"marketId"`
+	MessageType string      `json:"messageType"`
+	Timestamp   string      `json:"timestamp"`
 }
 
 // BTCMWSOrderbookUpdateEvent In many cases, it's more appropriate to maintain a local copy of
 // the exchange orderbook by receiving only updates instead of the entire orderbook.
+// A level with a zero volume is removed from the orderbook; see OrderbookEngine.
 type BTCMWSOrderbookUpdateEvent struct {
-	Asks        [][]interface{} `json:"asks"`
-	Bids        [][]interface{} `json:"bids"`
-	MarketID    string          `json:"marketId"`
-	MessageType string          `json:"messageType"`
-	Snapshot    bool            `json:"snapshot"`
-	SnapshotID  int64           `json:"snapshotId"`
-	Timestamp   string          `json:"timestamp"`
+	Asks        []OrderbookLevel `json:"asks"`
+	Bids        []OrderbookLevel `json:"bids"`
+	MarketID    string           `json:"marketId"`
+	MessageType string           `json:"messageType"`
+	Snapshot    bool             `json:"snapshot"`
+	SnapshotID  int64            `json:"snapshotId"`
+	Timestamp   string           `json:"timestamp"`
+	Checksum    json.Number      `json:"checksum"`
 }
 
 // BTCMWSTickResponse Response object res
 type BTCMWSTickResponse struct {
-	BestAsk     string `json:"bestAsk"`
-	BestBid     string `json:"bestBid"`
-	High24h     string `json:"high24h"`
-	LastPrice   string `json:"lastPrice"`
-	Low24h      string `json:"low24h"`
-	MarketID    string `json:"marketId"`
-	MessageType string `json:"messageType"`
-	Price24h    string `json:"price24h"`
-	SnapshotID  int64  `json:"snapshotId"`
-	Timestamp   string `json:"timestamp"`
-	Volume24h   string `json:"volume24h"`
+	BestAsk     Decimal `json:"bestAsk"`
+	BestBid     Decimal `json:"bestBid"`
+	High24h     Decimal `json:"high24h"`
+	LastPrice   Decimal `json:"lastPrice"`
+	Low24h      Decimal `json:"low24h"`
+	MarketID    string  `json:"marketId"`
+	MessageType string  `json:"messageType"`
+	Price24h    Decimal `json:"price24h"`
+	SnapshotID  int64   `json:"snapshotId"`
+	Timestamp   string  `json:"timestamp"`
+	Volume24h   Decimal `json:"volume24h"`
 }
 
 // BTCMWSHeartbeatEvent if you subscribe to heartbeat event
@@ -99,6 +101,48 @@ type BTCMWSErrorEvent struct {
 	MessageType string `json:"messageType"`
 }
 
+// BTCMWSOrderChangeEvent is published on the private orderChange channel
+// when an order of the account is placed, matched, cancelled or triggered.
+// Trades holds the fills of the order, if any. OrderID is sent as a number
+// by the exchange; its String method returns the id used by the REST API.
+type BTCMWSOrderChangeEvent struct {
+	OrderID       json.Number        `json:"orderId"`
+	ClientOrderID string             `json:"clientOrderId"`
+	MarketID      string             `json:"marketId"`
+	Side          Side               `json:"side"`
+	Type          OrderType          `json:"type"`
+	OpenVolume    Decimal            `json:"openVolume"`
+	Status        OrderStatus        `json:"status"`
+	TriggerStatus string             `json:"triggerStatus"`
+	Trades        []BTCMWSOrderTrade `json:"trades"`
+	Timestamp     string             `json:"timestamp"`
+	MessageType   string             `json:"messageType"`
+}
+
+// BTCMWSOrderTrade is a fill of the order of a BTCMWSOrderChangeEvent.
+// LiquidityType is Maker or Taker.
+type BTCMWSOrderTrade struct {
+	TradeID       int64   `json:"tradeId"`
+	Price         Decimal `json:"price"`
+	Volume        Decimal `json:"volume"`
+	Fee           Decimal `json:"fee"`
+	LiquidityType string  `json:"liquidityType"`
+}
+
+// BTCMWSFundChangeEvent is published on the private fundChange channel when
+// a deposit or withdrawal of the account is created or changes status. Type
+// is Deposit or Withdraw.
+type BTCMWSFundChangeEvent struct {
+	FundTransferID int64   `json:"fundtransferId"`
+	Type           string  `json:"type"`
+	Status         string  `json:"status"`
+	Amount         Decimal `json:"amount"`
+	Currency       string  `json:"currency"`
+	Fee            Decimal `json:"fee"`
+	Timestamp      string  `json:"timestamp"`
+	MessageType    string  `json:"messageType"`
+}
+
 // WSSubscribeMessage Subscribe message to initiate WebSocket Connection
 type WSSubscribeMessage struct {
 	Channels    []string `json:"channels"`
@@ -109,6 +153,17 @@ type WSSubscribeMessage struct {
 	Signature   string   `json:"signature"`
 }
 
+// requiresAuth reports whether any of the channels is private to the account
+// and needs a signed subscription.
+func requiresAuth(channels []string) bool {
+	for _, ch := range channels {
+		if ch == orderChange || ch == fundChange {
+			return true
+		}
+	}
+	return false
+}
+
 // WebSocketServiceOp WebSocket feed provides real-time market data covering
 //  orderbook updates, order life cycle and trades
 type WebSocketServiceOp struct {
@@ -120,54 +175,225 @@ type WebSocketServiceOp struct {
 // of the bytes returned on the channel.
 // This method needs to be called with a ContextWithCancel as first parameter to be able
 // close the websocket and a SubscribeMessage to start receiving events for the
-// specified channels and marketIds
+// specified channels and marketIds. The channel is closed when the context is
+// done or the connection is lost; see SubscribeEvents for a decoded stream
+// reporting errors separately.
 func (ws *WebSocketServiceOp) Subscribe(ctx context.Context, m WSSubscribeMessage) (chan []byte, error) {
 	wsmessages := make(chan []byte)
 
-	c, _, err := websocket.DefaultDialer.Dial(ws.client.WSURL.String(), nil)
+	c, err := ws.dial(ctx, m)
+	if err != nil {
+		return nil, err
+	}
 
+	closeFeed := ws.openOrderFeed(m)
+	go func() {
+		defer close(wsmessages)
+		err := ws.read(ctx, c, closeFeed, func(payload []byte) bool {
+			select {
+			case wsmessages <- payload:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			ws.client.logger.Error("error reading websocket message", "error", err)
+		}
+	}()
+
+	return wsmessages, nil
+}
+
+// WSStream is a decoded WebSocket feed returned by SubscribeEvents.
+type WSStream struct {
+	events chan WSEvent
+	status chan WSStatus
+}
+
+const wsStatusBuffer = 16
+
+func newWSStream() *WSStream {
+	return &WSStream{
+		events: make(chan WSEvent),
+		status: make(chan WSStatus, wsStatusBuffer),
+	}
+}
+
+// Events returns the channel of decoded events. It is closed when the
+// stream ends.
+func (s *WSStream) Events() <-chan WSEvent {
+	return s.events
+}
+
+// Status returns the channel of connection state changes and errors. It is
+// buffered and closed when the stream ends; statuses are dropped while the
+// buffer is full so a consumer ignoring it does not stall the events.
+func (s *WSStream) Status() <-chan WSStatus {
+	return s.status
+}
+
+func (s *WSStream) sendStatus(state WSState, err error) {
+	select {
+	case s.status <- WSStatus{State: state, Err: err, Time: time.Now()}:
+	default:
+	}
+}
+
+// sendEvent delivers e unless ctx is done first, and reports whether it did.
+func (s *WSStream) sendEvent(ctx context.Context, e WSEvent) bool {
+	select {
+	case s.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// handler returns the function decoding the messages of a connection into
+// events of s.
+func (s *WSStream) handler(ctx context.Context) func([]byte) bool {
+	return func(payload []byte) bool {
+		e, err := decodeWSEvent(payload)
+		if err != nil {
+			s.sendStatus(WSError, err)
+			return true
+		}
+		return s.sendEvent(ctx, e)
+	}
+}
+
+func (s *WSStream) close() {
+	close(s.events)
+	close(s.status)
+}
+
+// SubscribeEvents subscribes like Subscribe but decodes every message into
+// the event struct of its messageType, see WSEvent. WSConnected is reported
+// on the status channel once subscribed; messages that cannot be decoded
+// are reported as WSError and skipped. The stream ends with WSClosed when
+// ctx is done, or with WSDisconnected and the read error when the connection
+// is lost.
+func (ws *WebSocketServiceOp) SubscribeEvents(ctx context.Context, m WSSubscribeMessage) (*WSStream, error) {
+	c, err := ws.dial(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newWSStream()
+	s.sendStatus(WSConnected, nil)
+
+	closeFeed := ws.openOrderFeed(m)
+	go func() {
+		defer s.close()
+		err := ws.read(ctx, c, closeFeed, s.handler(ctx))
+		if ctx.Err() != nil {
+			s.sendStatus(WSClosed, nil)
+			return
+		}
+		ws.client.logger.Error("websocket connection lost", "error", err)
+		s.sendStatus(WSDisconnected, err)
+	}()
+
+	return s, nil
+}
+
+// dial opens a connection to the WebSocket feed and sends the subscribe
+// message m, signed when the client has credentials.
+func (ws *WebSocketServiceOp) dial(ctx context.Context, m WSSubscribeMessage) (*websocket.Conn, error) {
+	if ws.client.signer == nil && requiresAuth(m.Channels) {
+		return nil, ErrNoCredentials
+	}
+
+	c, _, err := websocket.DefaultDialer.DialContext(ctx, ws.client.WSURL.String(), nil)
+	if err != nil {
+		ws.client.logger.Error("error dialing websocket connection", "url", ws.client.WSURL.String(), "error", err)
+		return nil, err
+	}
+
+	m.MessageType = subscribe
+	if err := ws.sign(ctx, &m); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	err = c.WriteJSON(m)
 	if err != nil {
-		fmt.Println("Error Dialing WebSocket Connection: ", err.Error())
+		ws.client.logger.Error("error sending websocket subscribe message", "error", err)
+		c.Close()
 		return nil, err
 	}
+	return c, nil
+}
 
+// sign sets the key, timestamp and signature of a subscription message when
+// the client has credentials.
+func (ws *WebSocketServiceOp) sign(ctx context.Context, m *WSSubscribeMessage) error {
 	if len(ws.client.apiKey) > 0 {
 		m.Key = ws.client.apiKey
 	}
 
-	if len(ws.client.privateKey) > 0 {
-		t := strconv.FormatInt(time.Now().UTC().UnixNano()/1000000, 10)
+	if ws.client.signer != nil {
+		t := strconv.FormatInt(ws.client.clock.timestamp(), 10)
 		m.Timestamp = t
 		strToSign := "/users/self/subscribe" + "\n" + t
-		m.Signature = ws.client.signMessage(strToSign)
+		var err error
+		m.Signature, err = ws.client.signMessage(ctx, strToSign)
+		if err != nil {
+			return err
+		}
 	}
-	m.MessageType = "subscribe"
+	return nil
+}
 
-	err = c.WriteJSON(m)
-	if err != nil {
-		fmt.Println(err.Error())
-		return nil, err
+// openOrderFeed registers the subscription m as a source of orderChange
+// events of its markets for WaitForOrder, and returns the function closing
+// the feed, nil if m is not subscribed to orderChange.
+func (ws *WebSocketServiceOp) openOrderFeed(m WSSubscribeMessage) func() {
+	if !stringInArray(orderChange, m.Channels) {
+		return nil
+	}
+	markets := append([]string(nil), m.MarketIds...)
+	ws.client.orderWatch.feedUp(markets)
+	return func() { ws.client.orderWatch.feedDown(markets) }
+}
+
+// read passes the messages received on c to handle until handle returns
+// false, ctx is done or reading fails, and closes c. It returns the read
+// error, or the context error once ctx is done. When closeFeed is set,
+// orderChange messages are also dispatched to WaitForOrder and closeFeed,
+// returned by openOrderFeed, is called on return.
+func (ws *WebSocketServiceOp) read(ctx context.Context, c *websocket.Conn, closeFeed func(), handle func([]byte) bool) error {
+	defer c.Close()
+	watchOrders := closeFeed != nil
+	if watchOrders {
+		defer closeFeed()
 	}
 
+	// Closing the connection unblocks ReadMessage when ctx is done.
+	stop := make(chan struct{})
+	defer close(stop)
 	go func() {
-		defer c.Close()
-		defer close(wsmessages)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				_, payload, err := c.ReadMessage()
-				if err != nil {
-					fmt.Println(err.Error())
-					wsmessages <- []byte(err.Error())
-					// return
-					// TODO: check if this code block should return on error
-				}
-				wsmessages <- payload
-			}
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-stop:
 		}
 	}()
 
-	return wsmessages, nil
+	for {
+		_, payload, err := c.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if watchOrders {
+			ws.client.orderWatch.dispatch(payload)
+		}
+		if !handle(payload) {
+			return ctx.Err()
+		}
+	}
 }
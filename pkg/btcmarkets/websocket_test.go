@@ -0,0 +1,176 @@
+package btcmarkets
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveWS serves the WebSocket feed of the test server: every connection
+// receives its subscribe message on subscribed, then messages, and is
+// closed once messages is sent unless hold is set.
+func serveWS(mux *http.ServeMux, subscribed chan<- WSSubscribeMessage, hold <-chan struct{}, messages ...string) {
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		var m WSSubscribeMessage
+		if err := c.ReadJSON(&m); err != nil {
+			return
+		}
+		if subscribed != nil {
+			subscribed <- m
+		}
+		for _, msg := range messages {
+			c.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		if hold != nil {
+			<-hold
+		}
+	})
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveWS(mux, nil, nil,
+		`{"marketId":"BTC-AUD","bestBid":"13100","bestAsk":"13200.5","lastPrice":"13150","volume24h":"1.5","messageType":"tick"}`,
+		`{"marketId":"BTC-AUD","tradeId":4107372347,"price":"13150","volume":"0.1","side":"Ask","messageType":"trade"}`,
+		`{"messageType":"trade",`,
+		`{"marketId":"BTC-AUD","bids":[["13100","0.5"]],"asks":[["13200.5","1"]],"messageType":"orderbook"}`,
+		`{"channels":[{"name":"tick","marketIds":["BTC-AUD"]}],"messageType":"heartbeat"}`,
+		`{"code":3,"message":"invalid channel names","messageType":"error"}`,
+		`{"messageType":"somethingNew"}`,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.SubscribeEvents(ctx, WSSubscribeMessage{Channels: []string{tick, trade, wsOB, heartbeat}, MarketIds: []string{"BTC-AUD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WSEvent
+	for e := range s.Events() {
+		events = append(events, e)
+	}
+	if len(events) != 6 {
+		t.Fatalf("received %d events; want 6", len(events))
+	}
+	if e, ok := events[0].(*BTCMWSTickEvent); !ok || e.BestAsk.String() != "13200.5" {
+		t.Errorf("events[0] = %#v", events[0])
+	}
+	if e, ok := events[1].(*BTCMWSTradeEvent); !ok || e.Side != SideAsk || e.TradeID != 4107372347 {
+		t.Errorf("events[1] = %#v", events[1])
+	}
+	if e, ok := events[2].(*BTCMWSOrderbookEvent); !ok || len(e.Bids) != 1 {
+		t.Errorf("events[2] = %#v", events[2])
+	}
+	if e, ok := events[3].(*BTCMWSHeartbeatEvent); !ok || e.Channels[0].Name != tick {
+		t.Errorf("events[3] = %#v", events[3])
+	}
+	if e, ok := events[4].(*BTCMWSErrorEvent); !ok || e.Code != 3 {
+		t.Errorf("events[4] = %#v", events[4])
+	}
+	if e, ok := events[5].(*WSRawEvent); !ok || e.MessageType != "somethingNew" {
+		t.Errorf("events[5] = %#v", events[5])
+	}
+
+	var states []WSState
+	for st := range s.Status() {
+		states = append(states, st.State)
+	}
+	if len(states) != 3 || states[0] != WSConnected || states[1] != WSError || states[2] != WSDisconnected {
+		t.Errorf("states = %v; want [Connected Error Disconnected]", states)
+	}
+}
+
+func TestSubscribeEventsClosed(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hold := make(chan struct{})
+	defer close(hold)
+	serveWS(mux, nil, hold)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := client.WebSocket.SubscribeEvents(ctx, WSSubscribeMessage{Channels: []string{tick}, MarketIds: []string{"BTC-AUD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	for range s.Events() {
+	}
+	var last WSStatus
+	for st := range s.Status() {
+		last = st
+	}
+	if last.State != WSClosed {
+		t.Errorf("last state = %v; want Closed", last.State)
+	}
+}
+
+func TestSubscribeStopsOnReadError(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveWS(mux, nil, nil, `{"messageType":"heartbeat"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := client.WebSocket.Subscribe(ctx, WSSubscribeMessage{Channels: []string{heartbeat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []string
+	for msg := range ch {
+		msgs = append(msgs, string(msg))
+	}
+	if len(msgs) != 1 || msgs[0] != `{"messageType":"heartbeat"}` {
+		t.Errorf("messages = %q", msgs)
+	}
+}
+
+func TestDecodePrivateWSEvents(t *testing.T) {
+	e, err := decodeWSEvent([]byte(`{"orderId":79003,"clientOrderId":"c1","marketId":"BTC-AUD","side":"Bid","type":"Limit","openVolume":"0.5","status":"Partially Matched","triggerStatus":"","trades":[{"tradeId":31727,"price":"13150.5","volume":"0.5","fee":"0.03","liquidityType":"Taker"}],"timestamp":"2019-04-08T20:41:19.339Z","messageType":"orderChange"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc, ok := e.(*BTCMWSOrderChangeEvent)
+	if !ok {
+		t.Fatalf("event = %#v; want *BTCMWSOrderChangeEvent", e)
+	}
+	if oc.OrderID.String() != "79003" || oc.ClientOrderID != "c1" || oc.Side != SideBid || oc.Type != OrderTypeLimit || oc.Status != OrderStatusPartiallyMatched || oc.OpenVolume.String() != "0.5" {
+		t.Errorf("order change = %+v", oc)
+	}
+	if len(oc.Trades) != 1 {
+		t.Fatalf("trades = %+v", oc.Trades)
+	}
+	if tr := oc.Trades[0]; tr.TradeID != 31727 || tr.Price.String() != "13150.5" || tr.Fee.String() != "0.03" || tr.LiquidityType != "Taker" {
+		t.Errorf("trade = %+v", tr)
+	}
+
+	e, err = decodeWSEvent([]byte(`{"fundtransferId":276811,"type":"Deposit","status":"Complete","timestamp":"2019-04-16T01:38:02.931Z","amount":"0.001","currency":"BTC","fee":"0","messageType":"fundChange"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, ok := e.(*BTCMWSFundChangeEvent)
+	if !ok || fc.FundTransferID != 276811 || fc.Type != "Deposit" || fc.Status != "Complete" || fc.Amount.String() != "0.001" || fc.Currency != "BTC" || !fc.Fee.IsZero() {
+		t.Errorf("fund change = %#v", e)
+	}
+}
@@ -0,0 +1,137 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WSEvent is an event received from the WebSocket feed. Its dynamic type is
+// one of *BTCMWSTickEvent, *BTCMWSTradeEvent, *BTCMWSOrderbookEvent,
+// *BTCMWSOrderbookUpdateEvent, *BTCMWSHeartbeatEvent, *BTCMWSErrorEvent,
+// *BTCMWSOrderChangeEvent, *BTCMWSFundChangeEvent, *WSReconnectEvent or,
+// for message types the package does not decode, *WSRawEvent.
+type WSEvent interface {
+	wsEvent()
+}
+
+func (*BTCMWSTickEvent) wsEvent()            {}
+func (*BTCMWSTradeEvent) wsEvent()           {}
+func (*BTCMWSOrderbookEvent) wsEvent()       {}
+func (*BTCMWSOrderbookUpdateEvent) wsEvent() {}
+func (*BTCMWSHeartbeatEvent) wsEvent()       {}
+func (*BTCMWSErrorEvent) wsEvent()           {}
+func (*BTCMWSOrderChangeEvent) wsEvent()     {}
+func (*BTCMWSFundChangeEvent) wsEvent()      {}
+func (*WSRawEvent) wsEvent()                 {}
+func (*WSReconnectEvent) wsEvent()           {}
+
+// WSRawEvent holds a message of a type the package does not decode.
+type WSRawEvent struct {
+	MessageType string
+	Payload     json.RawMessage
+}
+
+// WSReconnectEvent is delivered by a WSSession, in order with the other
+// events, once the connection was re-established and the subscription sent
+// again. Events published while disconnected are lost, so state built from
+// the feed, e.g. a local orderbook, must be resynchronised.
+type WSReconnectEvent struct {
+	// Attempts is the number of connection attempts made since the session
+	// was last stable, see WSSessionOptions.MaxAttempts.
+	Attempts int
+	// Reconnects is the number of reconnections of the session so far.
+	Reconnects int64
+	Time       time.Time
+}
+
+// decodeWSEvent decodes payload into the event struct of its messageType.
+func decodeWSEvent(payload []byte) (WSEvent, error) {
+	var m struct {
+		MessageType string `json:"messageType"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, fmt.Errorf("btcmarkets: invalid websocket message: %w", err)
+	}
+
+	var e WSEvent
+	switch m.MessageType {
+	case tick:
+		e = &BTCMWSTickEvent{}
+	case trade:
+		e = &BTCMWSTradeEvent{}
+	case wsOB:
+		e = &BTCMWSOrderbookEvent{}
+	case wsOBUpdate:
+		e = &BTCMWSOrderbookUpdateEvent{}
+	case heartbeat:
+		e = &BTCMWSHeartbeatEvent{}
+	case wsError:
+		e = &BTCMWSErrorEvent{}
+	case orderChange:
+		e = &BTCMWSOrderChangeEvent{}
+	case fundChange:
+		e = &BTCMWSFundChangeEvent{}
+	default:
+		return &WSRawEvent{MessageType: m.MessageType, Payload: append(json.RawMessage(nil), payload...)}, nil
+	}
+
+	if err := json.Unmarshal(payload, e); err != nil {
+		return nil, fmt.Errorf("btcmarkets: invalid %s message: %w", m.MessageType, err)
+	}
+	return e, nil
+}
+
+// WSState is the state of a WebSocket connection reported on the status
+// channel of a stream.
+type WSState int
+
+// WebSocket connection states
+const (
+	// WSConnected is reported once the connection is open and the
+	// subscription message sent.
+	WSConnected WSState = iota
+	// WSError reports an error that did not close the connection, e.g. a
+	// message that could not be decoded.
+	WSError
+	// WSDisconnected is reported when the connection is lost.
+	WSDisconnected
+	// WSClosed is reported when the stream ends because its context is done
+	// or, for a WSSession, because it gave up reconnecting.
+	WSClosed
+	// WSReconnecting is reported by a WSSession before each reconnection
+	// attempt, with the error of the previous attempt if any.
+	WSReconnecting
+	// WSReconnected is reported by a WSSession once reconnected.
+	WSReconnected
+	// WSStale is reported by a WSSession, with ErrWSStale, when it closes a
+	// connection that went silent, before reconnecting.
+	WSStale
+)
+
+func (s WSState) String() string {
+	switch s {
+	case WSConnected:
+		return "Connected"
+	case WSError:
+		return "Error"
+	case WSDisconnected:
+		return "Disconnected"
+	case WSClosed:
+		return "Closed"
+	case WSReconnecting:
+		return "Reconnecting"
+	case WSReconnected:
+		return "Reconnected"
+	case WSStale:
+		return "Stale"
+	}
+	return fmt.Sprintf("WSState(%d)", int(s))
+}
+
+// WSStatus is a connection state change or an error of a WebSocket stream.
+type WSStatus struct {
+	State WSState
+	Err   error
+	Time  time.Time
+}
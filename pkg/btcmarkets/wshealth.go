@@ -0,0 +1,229 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// wsStableAfter is how long a connection must stay up, when it delivered
+// no message other than an error, before a WSSession deems it established
+// and resets its reconnection backoff.
+const wsStableAfter = 30 * time.Second
+
+// wsRateWindow is the number of seconds over which WSHealth.MessagesPerSecond
+// is averaged.
+const wsRateWindow = 10
+
+// ErrWSStale is the cause reported with WSStale and WSDisconnected when a
+// WSSession closes a connection that stayed silent for longer than
+// WSSessionOptions.StaleAfter.
+var ErrWSStale = errors.New("btcmarkets: websocket connection is stale")
+
+// WSHealth is a snapshot of the health of a WSSession, see WSSession.Health.
+type WSHealth struct {
+	// Connected is false while the session is reconnecting.
+	Connected   bool
+	ConnectedAt time.Time
+
+	// LastMessage is when the last message of any type was received on the
+	// current connection, zero if none was. LastMessageAge is the time
+	// elapsed since then, or since ConnectedAt when no message was received.
+	LastMessage    time.Time
+	LastMessageAge time.Duration
+
+	// LastHeartbeat is when the last heartbeat was received on the current
+	// connection, zero if none was. HeartbeatAge is only set when it is not.
+	LastHeartbeat time.Time
+	HeartbeatAge  time.Duration
+
+	// MessagesPerSecond is averaged over the last 10 seconds. Messages is
+	// the number of messages received by the session since it started.
+	MessagesPerSecond float64
+	Messages          int64
+
+	// Reconnects is the number of reconnections of the session, of which
+	// StaleReconnects were forced because the connection went silent.
+	Reconnects      int64
+	StaleReconnects int64
+
+	// Feeds holds the activity of every channel and market the session
+	// received messages for, sorted by channel then market.
+	Feeds []WSFeedHealth
+}
+
+// WSFeedHealth is the activity of one channel of one market, e.g. the tick
+// channel of BTC-AUD. MarketID is empty for messages not bound to a market,
+// like heartbeats.
+type WSFeedHealth struct {
+	Channel        string
+	MarketID       string
+	LastMessage    time.Time
+	LastMessageAge time.Duration
+	Messages       int64
+}
+
+type wsFeedKey struct {
+	channel, marketID string
+}
+
+type wsFeedStats struct {
+	last     time.Time
+	messages int64
+}
+
+// wsHealth tracks the messages received by a WSSession.
+type wsHealth struct {
+	mu            sync.Mutex
+	connectedAt   time.Time
+	lastMessage   time.Time
+	lastHeartbeat time.Time
+	messages      int64
+	feeds         map[wsFeedKey]*wsFeedStats
+
+	// established is set once the connection delivered a message other
+	// than an error.
+	established bool
+
+	// delivering is set while a message is handed to the consumer, during
+	// which the connection is not read. idleSince is when the connection
+	// was last read again after a delivery.
+	delivering bool
+	idleSince  time.Time
+
+	// counts holds the number of messages received during each of the
+	// last seconds, at the index of the second modulo wsRateWindow.
+	counts  [wsRateWindow]int64
+	seconds [wsRateWindow]int64
+}
+
+// connected resets the silence timers for a new connection.
+func (h *wsHealth) connected(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connectedAt = now
+	h.lastMessage = time.Time{}
+	h.lastHeartbeat = time.Time{}
+	h.established = false
+	h.delivering = false
+	h.idleSince = now
+}
+
+// record accounts for a message received at now, whose delivery to the
+// consumer starts. delivered must be called once it is over.
+func (h *wsHealth) record(now time.Time, payload []byte) {
+	var m struct {
+		MessageType string `json:"messageType"`
+		MarketID    string `json:"marketId"`
+	}
+	json.Unmarshal(payload, &m)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastMessage = now
+	h.delivering = true
+	if m.MessageType == heartbeat {
+		h.lastHeartbeat = now
+	}
+	if m.MessageType != wsError {
+		h.established = true
+	}
+	h.messages++
+
+	if h.feeds == nil {
+		h.feeds = make(map[wsFeedKey]*wsFeedStats)
+	}
+	k := wsFeedKey{m.MessageType, m.MarketID}
+	f := h.feeds[k]
+	if f == nil {
+		f = &wsFeedStats{}
+		h.feeds[k] = f
+	}
+	f.last = now
+	f.messages++
+
+	sec := now.Unix()
+	i := sec % wsRateWindow
+	if h.seconds[i] != sec {
+		h.seconds[i] = sec
+		h.counts[i] = 0
+	}
+	h.counts[i]++
+}
+
+// delivered records that the consumer took the last message at now, and
+// that the connection is read again.
+func (h *wsHealth) delivered(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.delivering = false
+	h.idleSince = now
+}
+
+// stable reports whether the connection was established, i.e. it delivered
+// a message other than an error or stayed up for wsStableAfter, as of now.
+func (h *wsHealth) stable(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.established || now.Sub(h.connectedAt) >= wsStableAfter
+}
+
+// silence returns how long the connection was read without receiving any
+// message as of now. The time spent waiting for the consumer to take a
+// message does not count, since the connection is not read meanwhile.
+func (h *wsHealth) silence(now time.Time) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.delivering {
+		return 0
+	}
+	return now.Sub(h.idleSince)
+}
+
+func (h *wsHealth) snapshot(now time.Time) WSHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := WSHealth{
+		ConnectedAt:   h.connectedAt,
+		LastMessage:   h.lastMessage,
+		LastHeartbeat: h.lastHeartbeat,
+		Messages:      h.messages,
+	}
+	if h.lastMessage.IsZero() {
+		r.LastMessageAge = now.Sub(h.connectedAt)
+	} else {
+		r.LastMessageAge = now.Sub(h.lastMessage)
+	}
+	if !h.lastHeartbeat.IsZero() {
+		r.HeartbeatAge = now.Sub(h.lastHeartbeat)
+	}
+
+	var n int64
+	for i, sec := range h.seconds {
+		if age := now.Unix() - sec; age >= 0 && age < wsRateWindow {
+			n += h.counts[i]
+		}
+	}
+	r.MessagesPerSecond = float64(n) / wsRateWindow
+
+	for k, f := range h.feeds {
+		r.Feeds = append(r.Feeds, WSFeedHealth{
+			Channel:        k.channel,
+			MarketID:       k.marketID,
+			LastMessage:    f.last,
+			LastMessageAge: now.Sub(f.last),
+			Messages:       f.messages,
+		})
+	}
+	sort.Slice(r.Feeds, func(i, j int) bool {
+		if r.Feeds[i].Channel != r.Feeds[j].Channel {
+			return r.Feeds[i].Channel < r.Feeds[j].Channel
+		}
+		return r.Feeds[i].MarketID < r.Feeds[j].MarketID
+	})
+	return r
+}
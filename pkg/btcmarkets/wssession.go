@@ -0,0 +1,426 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWSMinBackoff = time.Second
+	defaultWSMaxBackoff = time.Minute
+)
+
+// WSSessionOptions controls how a WSSession reconnects.
+type WSSessionOptions struct {
+	// MinBackoff is the wait before the first reconnection attempt. It
+	// doubles after every failed attempt until MaxBackoff is reached. They
+	// default to 1s and 1m.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of consecutive failed reconnection attempts
+	// after which the session gives up and ends. It retries forever when it
+	// is 0. A connection closed before it delivered anything but errors, or
+	// before it stayed up for 30 seconds, counts as a failed attempt and
+	// does not reset the backoff.
+	MaxAttempts int
+
+	// StaleAfter is the silence after which the connection is deemed stale:
+	// the session reports WSStale, closes it and reconnects. The time spent
+	// waiting for the consumer to read an event does not count as silence.
+	// Staleness is not checked when it is 0. The exchange only sends heartbeats every 5
+	// seconds to subscriptions of the heartbeat channel, so subscribe to it
+	// when the subscribed markets may be quiet for longer than StaleAfter.
+	StaleAfter time.Duration
+}
+
+// WSSession is a WebSocket subscription that survives disconnections:
+// when the connection is lost it reconnects with an exponential backoff,
+// signs the subscription again and resends it. Reconnections are reported
+// on the status channel and by a WSReconnectEvent on the events channel.
+//
+// Channels and markets can be added to or removed from a live session with
+// AddSubscription and RemoveSubscription. The session tracks the resulting
+// subscription and restores it when it reconnects.
+//
+// Health returns a snapshot of the activity of the session for monitoring.
+type WSSession struct {
+	*WSStream
+
+	ws      *WebSocketServiceOp
+	backoff RetryPolicy
+	max     int
+	stale   time.Duration
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// mu guards the subscription and the connection, which is nil while
+	// reconnecting, and serialises the writes on the connection.
+	mu   sync.Mutex
+	sub  WSSubscribeMessage
+	conn *websocket.Conn
+
+	health          wsHealth
+	reconnects      int64
+	staleReconnects int64
+	// orderFeed is 1 while the session counts as an orderChange feed of
+	// the client for feedMarkets, see syncOrderFeed.
+	orderFeed   int32
+	feedMarkets []string
+}
+
+// Connect opens a WSSession subscribed with m. It fails if the first
+// connection cannot be made; later disconnections are recovered from
+// according to opts, which may be nil for the defaults. The session ends
+// when ctx is done or Close is called.
+func (ws *WebSocketServiceOp) Connect(ctx context.Context, m WSSubscribeMessage, opts *WSSessionOptions) (*WSSession, error) {
+	var o WSSessionOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = defaultWSMinBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultWSMaxBackoff
+	}
+
+	c, err := ws.dial(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &WSSession{
+		WSStream: newWSStream(),
+		ws:       ws,
+		backoff:  RetryPolicy{MinBackoff: o.MinBackoff, MaxBackoff: o.MaxBackoff},
+		max:      o.MaxAttempts,
+		stale:    o.StaleAfter,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		sub:      m,
+	}
+	s.sendStatus(WSConnected, nil)
+
+	go s.run(ctx, c)
+	return s, nil
+}
+
+// Close ends the session and waits for its connection to be closed.
+func (s *WSSession) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Done returns a channel closed once the session has ended.
+func (s *WSSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Reconnects returns the number of times the session reconnected.
+func (s *WSSession) Reconnects() int64 {
+	return atomic.LoadInt64(&s.reconnects)
+}
+
+// Health returns a snapshot of the activity and reconnections of the
+// session.
+func (s *WSSession) Health() WSHealth {
+	h := s.health.snapshot(time.Now())
+	s.mu.Lock()
+	h.Connected = s.conn != nil
+	s.mu.Unlock()
+	h.Reconnects = s.Reconnects()
+	h.StaleReconnects = atomic.LoadInt64(&s.staleReconnects)
+	return h
+}
+
+// Subscription returns the channels and markets the session is currently
+// subscribed to.
+func (s *WSSession) Subscription() WSSubscribeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return WSSubscribeMessage{
+		Channels:  append([]string(nil), s.sub.Channels...),
+		MarketIds: append([]string(nil), s.sub.MarketIds...),
+	}
+}
+
+// AddSubscription subscribes the live session to more channels and
+// markets. The change is tracked even when the session is reconnecting, in
+// which case it is applied by the next subscription.
+func (s *WSSession) AddSubscription(ctx context.Context, channels, marketIDs []string) error {
+	return s.changeSubscription(ctx, addSubscription, channels, marketIDs)
+}
+
+// RemoveSubscription unsubscribes the live session from channels and
+// markets. The change is tracked even when the session is reconnecting, in
+// which case it is applied by the next subscription.
+func (s *WSSession) RemoveSubscription(ctx context.Context, channels, marketIDs []string) error {
+	return s.changeSubscription(ctx, removeSubscription, channels, marketIDs)
+}
+
+func (s *WSSession) changeSubscription(ctx context.Context, messageType string, channels, marketIDs []string) error {
+	if len(channels) == 0 && len(marketIDs) == 0 {
+		return errors.New("at least 1 channel or marketId is required")
+	}
+	if s.ws.client.signer == nil && requiresAuth(channels) {
+		return ErrNoCredentials
+	}
+
+	m := WSSubscribeMessage{Channels: channels, MarketIds: marketIDs, MessageType: messageType}
+	if err := s.ws.sign(ctx, &m); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if messageType == addSubscription {
+		s.sub.Channels = union(s.sub.Channels, channels)
+		s.sub.MarketIds = union(s.sub.MarketIds, marketIDs)
+	} else {
+		s.sub.Channels = difference(s.sub.Channels, channels)
+		s.sub.MarketIds = difference(s.sub.MarketIds, marketIDs)
+	}
+	s.syncOrderFeed()
+
+	if s.conn != nil {
+		if err := s.conn.WriteJSON(m); err != nil {
+			// The connection is lost: the reconnection restores the
+			// subscription tracked above.
+			s.ws.client.logger.Warn("error sending websocket subscription change", "messageType", messageType, "error", err)
+		}
+	}
+	return nil
+}
+
+// setConn records the connection of the session, nil while reconnecting.
+func (s *WSSession) setConn(c *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn = c
+	s.syncOrderFeed()
+}
+
+// syncOrderFeed counts the session as an orderChange feed of the markets
+// it is subscribed to, for WaitForOrder, while it is connected and
+// subscribed to orderChange. s.mu must be held.
+func (s *WSSession) syncOrderFeed() {
+	want := s.conn != nil && stringInArray(orderChange, s.sub.Channels)
+	open := atomic.LoadInt32(&s.orderFeed) == 1
+	if want && open && sameElements(s.feedMarkets, s.sub.MarketIds) {
+		return
+	}
+
+	if open {
+		s.ws.client.orderWatch.feedDown(s.feedMarkets)
+		s.feedMarkets = nil
+		atomic.StoreInt32(&s.orderFeed, 0)
+	}
+	if want {
+		s.feedMarkets = append([]string(nil), s.sub.MarketIds...)
+		s.ws.client.orderWatch.feedUp(s.feedMarkets)
+		atomic.StoreInt32(&s.orderFeed, 1)
+	}
+}
+
+// sessionHandler records the messages of the session in its health,
+// decodes them into events and dispatches orderChange messages to
+// WaitForOrder.
+func (s *WSSession) sessionHandler(ctx context.Context) func([]byte) bool {
+	h := s.handler(ctx)
+	return func(payload []byte) bool {
+		s.health.record(time.Now(), payload)
+		if atomic.LoadInt32(&s.orderFeed) == 1 {
+			s.ws.client.orderWatch.dispatch(payload)
+		}
+		ok := h(payload)
+		s.health.delivered(time.Now())
+		return ok
+	}
+}
+
+func (s *WSSession) run(ctx context.Context, c *websocket.Conn) {
+	defer close(s.done)
+	defer s.close()
+	defer s.setConn(nil)
+
+	// failures counts the reconnection attempts since the session was last
+	// stable, so a server accepting connections only to close them with an
+	// error does not reset the backoff.
+	failures := 0
+	s.setConn(c)
+	for {
+		s.health.connected(time.Now())
+		stop := s.watchStale(c)
+		err := s.ws.read(ctx, c, nil, s.sessionHandler(ctx))
+		stale := stop()
+		s.setConn(nil)
+		if ctx.Err() != nil {
+			s.sendStatus(WSClosed, nil)
+			return
+		}
+		if stale {
+			err = ErrWSStale
+			atomic.AddInt64(&s.staleReconnects, 1)
+		}
+		if s.health.stable(time.Now()) {
+			failures = 0
+		}
+		s.ws.client.logger.Warn("websocket connection lost, reconnecting", "error", err)
+		s.sendStatus(WSDisconnected, err)
+
+		c, failures, err = s.reconnect(ctx, err, failures)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.ws.client.logger.Error("websocket reconnection failed, closing the session", "error", err)
+			}
+			s.sendStatus(WSClosed, err)
+			return
+		}
+	}
+}
+
+// watchStale closes c once it stayed silent for longer than the StaleAfter
+// option. The returned function stops watching and reports whether c was
+// closed for being stale.
+func (s *WSSession) watchStale(c *websocket.Conn) func() bool {
+	if s.stale <= 0 {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	stale := make(chan bool, 1)
+	go func() {
+		interval := s.stale / 4
+		if interval <= 0 {
+			interval = s.stale
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				stale <- false
+				return
+			case now := <-t.C:
+				if silence := s.health.silence(now); silence > s.stale {
+					s.ws.client.logger.Warn("websocket connection is stale, reconnecting", "silence", silence)
+					s.sendStatus(WSStale, ErrWSStale)
+					c.Close()
+					<-done
+					stale <- true
+					return
+				}
+			}
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		return <-stale
+	}
+}
+
+// reconnect dials again until it succeeds, ctx is done or the maximum
+// number of attempts is reached, counting the failed previous attempts. It
+// returns the number of attempts made so far.
+func (s *WSSession) reconnect(ctx context.Context, cause error, failed int) (*websocket.Conn, int, error) {
+	for attempt := failed + 1; s.max == 0 || attempt <= s.max; attempt++ {
+		s.sendStatus(WSReconnecting, cause)
+		if err := sleepContext(ctx, s.backoff.backoff(attempt, nil)); err != nil {
+			return nil, attempt, err
+		}
+
+		sent := s.Subscription()
+		c, err := s.ws.dial(ctx, sent)
+		if err == nil {
+			err = s.attach(ctx, c, sent)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, attempt, ctx.Err()
+			}
+			cause = err
+			continue
+		}
+
+		n := atomic.AddInt64(&s.reconnects, 1)
+		s.ws.client.logger.Info("websocket reconnected", "attempts", attempt, "reconnects", n)
+		s.sendStatus(WSReconnected, nil)
+		if !s.sendEvent(ctx, &WSReconnectEvent{Attempts: attempt, Reconnects: n, Time: time.Now()}) {
+			c.Close()
+			return nil, attempt, ctx.Err()
+		}
+		return c, attempt, nil
+	}
+	return nil, failed, cause
+}
+
+// attach records c as the connection of the session, and brings it up to
+// date with the subscription changes made since it was subscribed with
+// sent, while it was being dialled.
+func (s *WSSession) attach(ctx context.Context, c *websocket.Conn, sent WSSubscribeMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := []WSSubscribeMessage{{
+		Channels:    difference(s.sub.Channels, sent.Channels),
+		MarketIds:   difference(s.sub.MarketIds, sent.MarketIds),
+		MessageType: addSubscription,
+	}, {
+		Channels:    difference(sent.Channels, s.sub.Channels),
+		MarketIds:   difference(sent.MarketIds, s.sub.MarketIds),
+		MessageType: removeSubscription,
+	}}
+	for _, m := range changes {
+		if len(m.Channels) == 0 && len(m.MarketIds) == 0 {
+			continue
+		}
+		err := s.ws.sign(ctx, &m)
+		if err == nil {
+			err = c.WriteJSON(m)
+		}
+		if err != nil {
+			c.Close()
+			return err
+		}
+	}
+
+	s.conn = c
+	s.syncOrderFeed()
+	return nil
+}
+
+// sameElements reports whether a and b hold the same elements.
+func sameElements(a, b []string) bool {
+	return len(difference(a, b)) == 0 && len(difference(b, a)) == 0
+}
+
+// union returns the elements of a followed by those of b missing from a.
+func union(a, b []string) []string {
+	u := append([]string(nil), a...)
+	for _, v := range b {
+		if !stringInArray(v, u) {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+// difference returns the elements of a missing from b.
+func difference(a, b []string) []string {
+	var d []string
+	for _, v := range a {
+		if !stringInArray(v, b) {
+			d = append(d, v)
+		}
+	}
+	return d
+}
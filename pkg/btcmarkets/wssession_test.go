@@ -0,0 +1,422 @@
+package btcmarkets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSSessionReconnects(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns int32
+	subscribed := make(chan WSSubscribeMessage, 2)
+	hold := make(chan struct{})
+	defer close(hold)
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var m WSSubscribeMessage
+		if err := c.ReadJSON(&m); err != nil {
+			return
+		}
+		subscribed <- m
+		c.WriteMessage(websocket.TextMessage, []byte(`{"marketId":"BTC-AUD","lastPrice":"1","messageType":"tick"}`))
+		if atomic.AddInt32(&conns, 1) > 1 {
+			<-hold
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.Connect(ctx, WSSubscribeMessage{Channels: []string{tick}, MarketIds: []string{"BTC-AUD"}},
+		&WSSessionOptions{MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WSEvent
+	for len(events) < 3 {
+		events = append(events, <-s.Events())
+	}
+	if _, ok := events[0].(*BTCMWSTickEvent); !ok {
+		t.Errorf("events[0] = %#v", events[0])
+	}
+	if e, ok := events[1].(*WSReconnectEvent); !ok || e.Reconnects != 1 {
+		t.Errorf("events[1] = %#v", events[1])
+	}
+	if _, ok := events[2].(*BTCMWSTickEvent); !ok {
+		t.Errorf("events[2] = %#v", events[2])
+	}
+	if s.Reconnects() != 1 {
+		t.Errorf("Reconnects() = %d; want 1", s.Reconnects())
+	}
+
+	first, second := <-subscribed, <-subscribed
+	if first.Signature == "" || first.Timestamp == second.Timestamp || first.Signature == second.Signature {
+		t.Errorf("subscription was not signed again: %+v, %+v", first, second)
+	}
+	if second.Channels[0] != tick || second.MarketIds[0] != "BTC-AUD" {
+		t.Errorf("resent subscription = %+v", second)
+	}
+
+	s.Close()
+	var states []WSState
+	for st := range s.Status() {
+		states = append(states, st.State)
+	}
+	want := []WSState{WSConnected, WSDisconnected, WSReconnecting, WSReconnected, WSClosed}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v; want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("states = %v; want %v", states, want)
+		}
+	}
+}
+
+func TestWSSessionGivesUp(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns int32
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&conns, 1) > 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		var m WSSubscribeMessage
+		c.ReadJSON(&m)
+		c.Close()
+	})
+
+	s, err := client.WebSocket.Connect(context.Background(), WSSubscribeMessage{Channels: []string{tick}},
+		&WSSessionOptions{MinBackoff: time.Millisecond, MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not give up")
+	}
+	var last WSStatus
+	for st := range s.Status() {
+		last = st
+	}
+	if last.State != WSClosed || last.Err == nil || atomic.LoadInt32(&conns) != 3 {
+		t.Errorf("last status = %+v after %d connections", last, conns)
+	}
+}
+
+func TestWSSessionGivesUpOnErrorClose(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection is accepted, then closed after an error.
+	var conns int32
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&conns, 1)
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var m WSSubscribeMessage
+		c.ReadJSON(&m)
+		c.WriteMessage(websocket.TextMessage, []byte(`{"code":1,"message":"authentication failed","messageType":"error"}`))
+	})
+
+	s, err := client.WebSocket.Connect(context.Background(), WSSubscribeMessage{Channels: []string{tick}},
+		&WSSessionOptions{MinBackoff: time.Millisecond, MaxAttempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range s.Events() {
+		}
+	}()
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		s.Close()
+		t.Fatalf("session did not give up after %d connections", atomic.LoadInt32(&conns))
+	}
+	if n := atomic.LoadInt32(&conns); n != 4 {
+		t.Errorf("connections = %d; want 4", n)
+	}
+}
+
+func TestWSSessionChangeSubscription(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns int32
+	received := make(chan WSSubscribeMessage, 10)
+	hold := make(chan struct{})
+	defer close(hold)
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if atomic.AddInt32(&conns, 1) > 1 {
+			var m WSSubscribeMessage
+			c.ReadJSON(&m)
+			received <- m
+			<-hold
+			return
+		}
+		// The first connection is dropped after the subscribe, add and
+		// remove messages.
+		for i := 0; i < 3; i++ {
+			var m WSSubscribeMessage
+			if err := c.ReadJSON(&m); err != nil {
+				return
+			}
+			received <- m
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.Connect(ctx, WSSubscribeMessage{Channels: []string{tick}, MarketIds: []string{"BTC-AUD"}},
+		&WSSessionOptions{MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go func() {
+		for range s.Events() {
+		}
+	}()
+
+	if m := <-received; m.MessageType != subscribe {
+		t.Fatalf("first message = %+v", m)
+	}
+	if err := s.AddSubscription(ctx, []string{trade, orderChange}, []string{"ETH-AUD"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-received; m.MessageType != addSubscription || len(m.Channels) != 2 || m.MarketIds[0] != "ETH-AUD" || m.Signature == "" {
+		t.Errorf("add message = %+v", m)
+	}
+	if !client.orderWatch.active("ETH-AUD") {
+		t.Error("adding orderChange should open an order feed")
+	}
+	if err := s.RemoveSubscription(ctx, []string{tick}, []string{"BTC-AUD"}); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-received; m.MessageType != removeSubscription || m.Channels[0] != tick {
+		t.Errorf("remove message = %+v", m)
+	}
+	if client.orderWatch.active("BTC-AUD") {
+		t.Error("removing BTC-AUD should close its order feed")
+	}
+
+	m := <-received
+	if m.MessageType != subscribe || len(m.Channels) != 2 || m.Channels[0] != trade || m.Channels[1] != orderChange ||
+		len(m.MarketIds) != 1 || m.MarketIds[0] != "ETH-AUD" {
+		t.Errorf("resubscription = %+v; want [trade orderChange] on [ETH-AUD]", m)
+	}
+	if sub := s.Subscription(); len(sub.Channels) != 2 || len(sub.MarketIds) != 1 {
+		t.Errorf("Subscription() = %+v", sub)
+	}
+
+	if err := s.AddSubscription(ctx, nil, nil); err == nil {
+		t.Error("AddSubscription without channels or markets should fail")
+	}
+}
+
+func TestWSSessionStale(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hold := make(chan struct{})
+	defer close(hold)
+	serveWS(mux, nil, hold,
+		`{"channels":[{"name":"tick","marketIds":["BTC-AUD"]}],"messageType":"heartbeat"}`,
+		`{"marketId":"BTC-AUD","lastPrice":"1","messageType":"tick"}`,
+		`{"marketId":"ETH-AUD","lastPrice":"1","messageType":"tick"}`,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.Connect(ctx, WSSubscribeMessage{Channels: []string{tick, heartbeat}, MarketIds: []string{"BTC-AUD", "ETH-AUD"}},
+		&WSSessionOptions{MinBackoff: time.Millisecond, StaleAfter: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		<-s.Events()
+	}
+	h := s.Health()
+	if !h.Connected || h.Messages != 3 || h.MessagesPerSecond != 0.3 || h.LastHeartbeat.IsZero() || h.StaleReconnects != 0 {
+		t.Errorf("Health() = %+v", h)
+	}
+	if len(h.Feeds) != 3 || h.Feeds[0].Channel != heartbeat || h.Feeds[1].MarketID != "BTC-AUD" || h.Feeds[2].MarketID != "ETH-AUD" {
+		t.Errorf("Feeds = %+v", h.Feeds)
+	}
+
+	// The server goes silent: the session reconnects.
+	if e, ok := (<-s.Events()).(*WSReconnectEvent); !ok || e.Reconnects != 1 {
+		t.Fatalf("event = %#v; want a reconnection", e)
+	}
+	<-s.Events()
+	if h := s.Health(); h.Reconnects != 1 || h.StaleReconnects != 1 || h.Messages < 4 {
+		t.Errorf("Health() = %+v", h)
+	}
+
+	want := []WSState{WSConnected, WSStale, WSDisconnected, WSReconnecting, WSReconnected}
+	for _, w := range want {
+		if st := <-s.Status(); st.State != w {
+			t.Errorf("status = %+v; want %v", st, w)
+		} else if w == WSDisconnected && !errors.Is(st.Err, ErrWSStale) {
+			t.Errorf("disconnection cause = %v; want ErrWSStale", st.Err)
+		}
+	}
+}
+
+func TestWSSessionChangeSubscriptionWhileReconnecting(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns int32
+	received := make(chan WSSubscribeMessage, 10)
+	hold := make(chan struct{})
+	defer close(hold)
+	mux.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if atomic.AddInt32(&conns, 1) == 1 {
+			// The first connection is dropped once subscribed.
+			var m WSSubscribeMessage
+			c.ReadJSON(&m)
+			return
+		}
+		for {
+			var m WSSubscribeMessage
+			if err := c.ReadJSON(&m); err != nil {
+				return
+			}
+			received <- m
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.Connect(ctx, WSSubscribeMessage{Channels: []string{tick}, MarketIds: []string{"BTC-AUD"}},
+		&WSSessionOptions{MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for st := range s.Status() {
+		if st.State == WSReconnected {
+			break
+		}
+	}
+	// The WSReconnectEvent is not read yet.
+	if err := s.AddSubscription(ctx, []string{trade}, []string{"ETH-AUD"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := <-received; m.MessageType != subscribe {
+		t.Fatalf("first message = %+v", m)
+	}
+	select {
+	case m := <-received:
+		if m.MessageType != addSubscription || m.Channels[0] != trade || m.MarketIds[0] != "ETH-AUD" {
+			t.Errorf("add message = %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the subscription change was not sent on the new connection")
+	}
+	if e, ok := (<-s.Events()).(*WSReconnectEvent); !ok {
+		t.Errorf("event = %#v; want a reconnection", e)
+	}
+}
+
+func TestWSSessionSlowConsumerIsNotStale(t *testing.T) {
+	client, mux, _, teardown, err := setup(nil)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hold := make(chan struct{})
+	defer close(hold)
+	serveWS(mux, nil, hold,
+		`{"marketId":"BTC-AUD","lastPrice":"1","messageType":"tick"}`,
+		`{"marketId":"BTC-AUD","lastPrice":"2","messageType":"tick"}`,
+		`{"marketId":"BTC-AUD","lastPrice":"3","messageType":"tick"}`,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := client.WebSocket.Connect(ctx, WSSubscribeMessage{Channels: []string{tick}, MarketIds: []string{"BTC-AUD"}},
+		&WSSessionOptions{MinBackoff: time.Millisecond, StaleAfter: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Each event is taken long after it was received, e.g. by a consumer
+	// resyncing an orderbook.
+	for i := 1; i <= 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		e, ok := (<-s.Events()).(*BTCMWSTickEvent)
+		if !ok || e.LastPrice.String() != strconv.Itoa(i) {
+			t.Fatalf("event %d = %#v", i, e)
+		}
+	}
+	for len(s.Status()) > 0 {
+		if st := <-s.Status(); st.State != WSConnected {
+			t.Errorf("status = %+v; want no disconnection", st)
+		}
+	}
+	if h := s.Health(); h.Reconnects != 0 || h.StaleReconnects != 0 {
+		t.Errorf("Health() = %+v; want no reconnection", h)
+	}
+}